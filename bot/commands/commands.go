@@ -9,7 +9,11 @@ var Commands = []*discordgo.ApplicationCommand{
 	&configCommand,
 	&birthdayCommand,
 	&pinCommand,
+	&unpinCommand,
 	&treeCommand,
+	&invitesCommand,
+	&statsCommand,
+	&pinsCommand,
 }
 
 var welcomeCommand = discordgo.ApplicationCommand{
@@ -30,6 +34,62 @@ var welcomeCommand = discordgo.ApplicationCommand{
 				},
 			},
 		},
+		{
+			Name:        "test_dm",
+			Description: "Send the welcome DM to yourself",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        "test_goodbye",
+			Description: "Test goodbye message",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "User to say goodbye to",
+					Required:    false,
+				},
+				&goodbyeTypeOption,
+			},
+		},
+	},
+}
+
+var (
+	minWelcomeMessageWeight   float64 = 1
+	maxWelcomeMessageWeight   float64 = 100
+	minWelcomeMessageNoRepeat float64 = 0
+	maxWelcomeMessageNoRepeat float64 = 25
+	minAutoRoleDelay          float64 = 0
+	maxAutoRoleDelay          float64 = 3600
+	minRaidJoinThreshold      float64 = 0
+	maxRaidJoinThreshold      float64 = 100
+	minRaidJoinWindow         float64 = 1
+	maxRaidJoinWindow         float64 = 600
+	minPinsPage               float64 = 1
+	minStarboardThreshold     float64 = 1
+	maxStarboardThreshold     float64 = 100
+)
+
+var goodbyeTypeOption = discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        "type",
+	Description: "How the member left, defaults to leave",
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "leave", Value: "leave"},
+		{Name: "kick", Value: "kick"},
+		{Name: "ban", Value: "ban"},
+	},
+}
+
+var autoRoleTargetOption = discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        "target",
+	Description: "Who the role is given to, defaults to humans",
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "humans", Value: "humans"},
+		{Name: "bots", Value: "bots"},
 	},
 }
 
@@ -44,6 +104,383 @@ var configCommand = discordgo.ApplicationCommand{
 			Name:        "list",
 			Description: "Lists available config options with their current values",
 		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "welcome",
+			Description: "Configures welcome messages",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "embed",
+					Description: "Edit the welcome embed, leave every field empty to remove it",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "card",
+					Description: "Configure the generated welcome card, which replaces the welcome message attachment",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether to send a welcome card",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "background_color",
+							Description: "Background color, e.g. #36393F",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "background_url",
+							Description: "Background image url, \"none\" to remove it",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "welcome_messages",
+			Description: "Manages the pool of welcome messages picked from at random",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Add a welcome message to the pool",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "message",
+							Description: "Welcome message, supports the same placeholders as welcome_message",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "weight",
+							Description: "How likely the message is picked compared to the others, defaults to 1",
+							MinValue:    &minWelcomeMessageWeight,
+							MaxValue:    maxWelcomeMessageWeight,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove a welcome message from the pool",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionInteger,
+							Name:         "id",
+							Description:  "ID of the welcome message",
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List the welcome messages in the pool",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "no_repeat",
+					Description: "Set how many recently used messages are skipped when picking",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "count",
+							Description: "Number of recently used messages to skip",
+							Required:    true,
+							MinValue:    &minWelcomeMessageNoRepeat,
+							MaxValue:    maxWelcomeMessageNoRepeat,
+						},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "auto_roles",
+			Description: "Manages the roles given to new members",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Give a role to new members",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "Role to give",
+							Required:    true,
+						},
+						&autoRoleTargetOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Stop giving a role to new members",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "Role to stop giving",
+							Required:    true,
+						},
+						&autoRoleTargetOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List the roles given to new members",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "delay",
+					Description: "Set how long to wait before giving the roles",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "seconds",
+							Description: "Delay in seconds, counted from passing membership screening",
+							Required:    true,
+							MinValue:    &minAutoRoleDelay,
+							MaxValue:    maxAutoRoleDelay,
+						},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "raids",
+			Description: "Configures join raid detection",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "threshold",
+					Description: "Set how many joins in a row batch welcomes into one",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "joins",
+							Description: "Joins that start a raid when exceeded, 0 disables raid detection",
+							Required:    true,
+							MinValue:    &minRaidJoinThreshold,
+							MaxValue:    maxRaidJoinThreshold,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "seconds",
+							Description: "Window the joins are counted in",
+							Required:    true,
+							MinValue:    &minRaidJoinWindow,
+							MaxValue:    maxRaidJoinWindow,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "alert_channel",
+					Description: "Set where moderators are alerted about raids",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionChannel,
+							Name:        "channel",
+							Description: "Alert channel, leave empty to stop alerting",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "starboard",
+			Description: "Configures pinning messages by reactions",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "enable",
+					Description: "Pin messages to the pins channel once they get enough reactions",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "threshold",
+							Description: "Reactions needed to pin a message",
+							Required:    true,
+							MinValue:    &minStarboardThreshold,
+							MaxValue:    maxStarboardThreshold,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "emoji",
+							Description: "Emoji to react with, defaults to ⭐",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "self_star",
+							Description: "Count the author's own reaction, defaults to false",
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "disable",
+					Description: "Stop pinning messages by reactions",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "pins",
+			Description: "Configures the pins channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "native",
+					Description: "Copy messages pinned with Discord's own pins to the pins channel",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "mirror",
+							Description: "Copy natively pinned messages",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "unpin",
+							Description: "Unpin messages once copied so that channels never reach the pin limit, defaults to false",
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "sync",
+					Description: "Update pinned messages when their originals are edited or deleted",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Keep pinned messages in sync",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "attribution",
+					Description: "Set who pinned messages are sent as",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "as_author",
+							Description: "Send pins as their author with the pinner in the footer, instead of as the pinner",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "role_add",
+					Description: "Let a role pin without approval, pinning is open to all until a pin role or approval channel is set",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "Role that can pin",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "role_remove",
+					Description: "Stop letting members with a role pin messages without approval",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "Role that can no longer pin",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "role_list",
+					Description: "List the roles that can pin messages without approval",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "approval",
+					Description: "Make members without a pin role request pins, reviewed by moderators in a channel",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Channel pin requests are sent to, leave empty to turn requests off",
+							ChannelTypes: pinRouteChannelTypes,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "route_add",
+					Description: "Send pins from a channel or category to another pins channel",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "source",
+							Description:  "Channel or category the pins come from",
+							Required:     true,
+							ChannelTypes: pinRouteSourceChannelTypes,
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Pins channel to send them to",
+							Required:     true,
+							ChannelTypes: pinRouteChannelTypes,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "route_remove",
+					Description: "Send pins from a channel or category to the default pins channel again",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "source",
+							Description:  "Channel or category the pins come from",
+							Required:     true,
+							ChannelTypes: pinRouteSourceChannelTypes,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "route_list",
+					Description: "List where pins from each channel are sent",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "goodbye",
+			Description: "Configures goodbye messages",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "embed",
+					Description: "Edit the goodbye embed, leave every field empty to remove it",
+				},
+			},
+		},
 		{
 			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
 			Name:        "set",
@@ -57,7 +494,7 @@ var configCommand = discordgo.ApplicationCommand{
 						{
 							Type:        discordgo.ApplicationCommandOptionString,
 							Name:        "message",
-							Description: "New welcome message",
+							Description: "New welcome message, supports placeholders like {mention} and {if new_account}...{end}",
 							Required:    true,
 						},
 					},
@@ -67,11 +504,15 @@ var configCommand = discordgo.ApplicationCommand{
 					Name:        "welcome_message_attachment",
 					Description: "Set Welcome Message Attachment",
 					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionAttachment,
+							Name:        "attachment",
+							Description: "New attachment, an image or video",
+						},
 						{
 							Type:        discordgo.ApplicationCommandOptionString,
 							Name:        "attachment_url",
-							Description: "New attachment url",
-							Required:    true,
+							Description: "New attachment url, \"none\" to remove it",
 						},
 					},
 				},
@@ -101,6 +542,90 @@ var configCommand = discordgo.ApplicationCommand{
 						},
 					},
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "birthday_channel_id",
+					Description: "Set Birthday Channel ID",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionChannel,
+							Name:        "channel",
+							Description: "New birthday channel",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "welcome_dm_message",
+					Description: "Set Welcome DM Message",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "message",
+							Description: "New welcome DM message, supports the same placeholders as welcome_message",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "welcome_delivery",
+					Description: "Set where welcomes are sent",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "mode",
+							Description: "Welcome channel, DMs or both",
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "channel", Value: "channel"},
+								{Name: "dm", Value: "dm"},
+								{Name: "both", Value: "both"},
+							},
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "welcome_after_screening",
+					Description: "Set whether welcomes wait until members accept the server rules",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Wait for membership screening to be completed",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "goodbye_channel_id",
+					Description: "Set Goodbye Channel ID",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionChannel,
+							Name:        "channel",
+							Description: "New goodbye channel",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "goodbye_message",
+					Description: "Set Goodbye Message",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "message",
+							Description: "New goodbye message, supports placeholders like {username} and {if reason}...{end}",
+							Required:    true,
+						},
+						&goodbyeTypeOption,
+					},
+				},
 			},
 		},
 	},
@@ -111,8 +636,20 @@ var (
 	maxMonth float64 = 12
 	minDay   float64 = 1
 	maxDay   float64 = 31
+	minYear  float64 = 1900
+	maxYear  float64 = 2100
 )
 
+var birthdayTemplateScopeOption = discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        "scope",
+	Description: "Whether the template applies to your reminders or to reminders added in this server",
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "personal", Value: "personal"},
+		{Name: "server", Value: "server"},
+	},
+}
+
 var birthdayCommand = discordgo.ApplicationCommand{
 	Name:        "birthday",
 	Description: "Various commands relating to birthdays",
@@ -122,18 +659,6 @@ var birthdayCommand = discordgo.ApplicationCommand{
 			Name:        "add",
 			Description: "Add birthday entry",
 			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "user_id",
-					Description: "ID of user",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "name",
-					Description: "Name of user",
-					Required:    true,
-				},
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "month",
@@ -150,6 +675,28 @@ var birthdayCommand = discordgo.ApplicationCommand{
 					MinValue:    &minDay,
 					MaxValue:    maxDay,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "User to add, use user_id instead for users outside this server",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "user_id",
+					Description: "ID or mention of user",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Name of user, defaults to their display name",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "year",
+					Description: "Birth year",
+					MinValue:    &minYear,
+					MaxValue:    maxYear,
+				},
 			},
 		},
 		{
@@ -158,10 +705,11 @@ var birthdayCommand = discordgo.ApplicationCommand{
 			Description: "Update birthday entry",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "user_id",
-					Description: "ID of user to update",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "user_id",
+					Description:  "ID or mention of user to update",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -182,6 +730,13 @@ var birthdayCommand = discordgo.ApplicationCommand{
 					MinValue:    &minDay,
 					MaxValue:    maxDay,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "year",
+					Description: "New birth year",
+					MinValue:    &minYear,
+					MaxValue:    maxYear,
+				},
 			},
 		},
 		{
@@ -190,10 +745,11 @@ var birthdayCommand = discordgo.ApplicationCommand{
 			Description: "Delete birthday entry",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "user_id",
-					Description: "ID of user",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "user_id",
+					Description:  "ID or mention of user",
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -202,16 +758,69 @@ var birthdayCommand = discordgo.ApplicationCommand{
 			Name:        "list",
 			Description: "List birthday entries",
 		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "template",
+			Description: "Customize birthday reminder messages",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Set reminder template, placeholders: {name}, {mention}, {user_id}, {age}, {days_until}",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "template",
+							Description: "New reminder template",
+							Required:    true,
+						},
+						&birthdayTemplateScopeOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "preview",
+					Description: "Preview reminder template",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "template",
+							Description: "Template to preview, defaults to the one used for your reminders",
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "reset",
+					Description: "Reset reminder template to the default",
+					Options: []*discordgo.ApplicationCommandOption{
+						&birthdayTemplateScopeOption,
+					},
+				},
+			},
+		},
 	},
 }
 
-var pinPermissions int64 = discordgo.PermissionManageMessages
+var pinRouteChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+}
+
+var pinRouteSourceChannelTypes = append([]discordgo.ChannelType{discordgo.ChannelTypeGuildCategory}, pinRouteChannelTypes...)
 
+// Available to everyone, the handler limits it to moderators and pin roles or turns it into a request
 var pinCommand = discordgo.ApplicationCommand{
-	Name:                     "Pin Message",
-	DMPermission:             &noDM,
-	DefaultMemberPermissions: &pinPermissions,
-	Type:                     discordgo.MessageApplicationCommand,
+	Name:         "Pin Message",
+	DMPermission: &noDM,
+	Type:         discordgo.MessageApplicationCommand,
+}
+
+// Available to everyone, the handler limits it to the pinner and moderators
+var unpinCommand = discordgo.ApplicationCommand{
+	Name:         "Unpin Message",
+	DMPermission: &noDM,
+	Type:         discordgo.MessageApplicationCommand,
 }
 
 var treeCommand = discordgo.ApplicationCommand{
@@ -289,3 +898,85 @@ var treeCommand = discordgo.ApplicationCommand{
 		},
 	},
 }
+
+var invitesCommand = discordgo.ApplicationCommand{
+	Name:         "invites",
+	Description:  "Various commands related to invites",
+	DMPermission: &noDM,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "leaderboard",
+			Description: "Show who invited the most members",
+		},
+	},
+}
+
+var statsCommand = discordgo.ApplicationCommand{
+	Name:         "stats",
+	Description:  "Various commands related to server statistics",
+	DMPermission: &noDM,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "members",
+			Description: "Chart member joins and leaves",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "range",
+					Description: "How far back to look, defaults to 30 days",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "7 days", Value: "7d"},
+						{Name: "30 days", Value: "30d"},
+						{Name: "90 days", Value: "90d"},
+						{Name: "365 days", Value: "365d"},
+					},
+				},
+			},
+		},
+	},
+}
+
+var pinsPageOption = discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionInteger,
+	Name:        "page",
+	Description: "Page to start at, defaults to the first",
+	MinValue:    &minPinsPage,
+}
+
+var pinsCommand = discordgo.ApplicationCommand{
+	Name:         "pins",
+	Description:  "Various commands related to pinned messages",
+	DMPermission: &noDM,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List pinned messages, newest first",
+			Options: []*discordgo.ApplicationCommandOption{
+				&pinsPageOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "search",
+			Description: "Search pinned messages by content",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "query",
+					Description: "Text the pinned message contains",
+					Required:    true,
+					MaxLength:   64,
+				},
+				&pinsPageOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "random",
+			Description: "Show a random pinned message",
+		},
+	},
+}
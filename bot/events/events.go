@@ -2,12 +2,10 @@ package events
 
 import (
 	"errors"
-	"fmt"
 	"kodachi/bot/models"
+	"kodachi/utils"
 	"log"
-	"net/http"
-	"net/url"
-	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"gorm.io/gorm"
@@ -15,6 +13,9 @@ import (
 
 func WelcomeMessageEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
 	return func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
+		// Recorded first so that the welcome can mention the inviter
+		recordMemberJoin(db, s, e.GuildID, e.User)
+
 		var guildConfig = models.Config{}
 
 		result := db.Where(&models.Config{GuildId: e.GuildID}).First(&guildConfig)
@@ -24,55 +25,227 @@ func WelcomeMessageEventHandler(db *gorm.DB) func(s *discordgo.Session, e *disco
 			log.Println("Server is not configured.")
 		case result.Error != nil:
 			log.Print(result.Error)
+		// Welcomed by PendingMemberUpdateEventHandler once they pass membership screening
+		case e.Pending && guildConfig.WelcomeAfterScreening:
+			return
 		default:
-			if guildConfig.WelcomeChannelId == "" {
-				log.Printf("Welcome channel is not configured")
-				return
-			}
+			welcomeMember(db, s, guildConfig, e.User)
+		}
+	}
+}
+
+// Sends the channel and DM welcomes configured for a guild
+func welcomeMember(db *gorm.DB, s *discordgo.Session, guildConfig models.Config, user *discordgo.User) {
+	if batchRaidWelcome(s, guildConfig, user) {
+		return
+	}
+
+	delivery := guildConfig.WelcomeDelivery
+
+	if delivery == "" || delivery == utils.WelcomeDeliveryChannel || delivery == utils.WelcomeDeliveryBoth {
+		sendChannelWelcome(db, s, guildConfig, user)
+	}
+
+	if delivery == utils.WelcomeDeliveryDM || delivery == utils.WelcomeDeliveryBoth {
+		sendDMWelcome(db, s, guildConfig, user)
+	}
+}
+
+func sendChannelWelcome(db *gorm.DB, s *discordgo.Session, guildConfig models.Config, user *discordgo.User) {
+	if guildConfig.WelcomeChannelId == "" {
+		log.Printf("Welcome channel is not configured")
+		return
+	}
+
+	welcomeMessage, err := utils.PickWelcomeMessage(db, guildConfig, true)
+
+	if err != nil {
+		log.Printf("An error occurred while picking welcome message: %v", err)
+		return
+	}
+
+	guildConfig.WelcomeMessage = welcomeMessage
+
+	if guildConfig.WelcomeMessage == "" && !utils.WelcomeEmbedTemplate(guildConfig).IsSet() {
+		log.Printf("Welcome message is not configured")
+		return
+	}
+
+	messageContent, messageEmbed, err := utils.RenderWelcome(db, s, guildConfig, user)
+
+	if err != nil {
+		log.Printf("Welcome message of %v is invalid: %v", guildConfig.GuildId, err)
+		return
+	}
+
+	var messageEmbeds []*discordgo.MessageEmbed
+
+	if messageEmbed != nil {
+		messageEmbeds = []*discordgo.MessageEmbed{messageEmbed}
+	}
+
+	var messageFiles []*discordgo.File
+
+	if guildConfig.WelcomeCardEnabled {
+		card, err := utils.WelcomeCard(db, s, guildConfig, user)
+
+		if err != nil {
+			log.Printf("An error occurred while rendering welcome card: %v", err)
+		} else {
+			messageFiles = []*discordgo.File{card}
+		}
+	} else {
+		attachment, err := utils.WelcomeAttachment(db, guildConfig)
+
+		switch {
+		case err != nil:
+			log.Printf("Failed to load welcome attachment of %v: %v", guildConfig.GuildId, err)
+		case attachment != nil:
+			messageFiles = []*discordgo.File{attachment}
+		}
+	}
+
+	_, err = s.ChannelMessageSendComplex(guildConfig.WelcomeChannelId, &discordgo.MessageSend{
+		Content: messageContent,
+		Embeds:  messageEmbeds,
+		Files:   messageFiles,
+	})
+
+	if err != nil {
+		log.Printf("Failed to send welcome message in %v: %v", guildConfig.GuildId, err)
+	}
+}
+
+// Sends the DM welcome, recording a failure if the member doesn't accept DMs
+func sendDMWelcome(db *gorm.DB, s *discordgo.Session, guildConfig models.Config, user *discordgo.User) {
+	if guildConfig.WelcomeDMMessage == "" {
+		log.Printf("Welcome DM message is not configured")
+		return
+	}
+
+	err := utils.SendDM(s, user.ID, &discordgo.MessageSend{
+		Content: utils.RenderWelcomeTemplate(guildConfig.WelcomeDMMessage, utils.WelcomeTemplateValues(db, s, guildConfig.GuildId, user)),
+	})
+
+	if err != nil {
+		log.Printf("Failed to send welcome DM to %v in %v: %v", user.ID, guildConfig.GuildId, err)
+
+		reason := err.Error()
+
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeCannotSendMessagesToThisUser {
+			reason = "DMs closed"
+		}
 
-			if guildConfig.WelcomeMessage == "" {
-				log.Printf("Welcome message is not configured")
+		result := db.Create(&models.WelcomeDMFailure{
+			GuildId: guildConfig.GuildId,
+			UserId:  user.ID,
+			Reason:  reason,
+		})
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
+
+func GoodbyeMessageEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+		var guildConfig = models.Config{}
+
+		result := db.Where(&models.Config{GuildId: e.GuildID}).First(&guildConfig)
+
+		switch {
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			log.Println("Server is not configured.")
+		case result.Error != nil:
+			log.Print(result.Error)
+		default:
+			if guildConfig.GoodbyeChannelId == "" {
 				return
 			}
 
-			messageContent := strings.ReplaceAll(guildConfig.WelcomeMessage, "<@USER_ID>", fmt.Sprintf("<@%s>", e.User.ID))
-
-			var messageFiles []*discordgo.File
+			reason := memberRemovalReason(s, e.GuildID, e.User.ID)
 
-			if guildConfig.WelcomeMessageAttachmentURL != "" {
-				validAttachmentURL, err := url.ParseRequestURI(guildConfig.WelcomeMessageAttachmentURL)
-				if err != nil {
-					s.ChannelMessageSendComplex(guildConfig.WelcomeChannelId, &discordgo.MessageSend{
-						Content: "Attachment url is invalid.",
-					})
-					return
-				}
+			if utils.GoodbyeMessage(guildConfig, reason.Kind) == "" && !utils.GoodbyeEmbedTemplate(guildConfig).IsSet() {
+				log.Printf("Goodbye message is not configured")
+				return
+			}
 
-				resp, err := http.Get(validAttachmentURL.String())
+			messageContent, messageEmbed, err := utils.RenderGoodbye(s, guildConfig, e.User, reason)
 
-				if err != nil {
-					log.Printf("An error occurred while fetching image: %v", err)
-				}
+			if err != nil {
+				log.Printf("Goodbye message of %v is invalid: %v", e.GuildID, err)
+				return
+			}
 
-				messageFiles = []*discordgo.File{
-					{
-						ContentType: resp.Header.Get("Content-Type"),
-						Name:        "welcome.png",
-						Reader:      resp.Body,
-					},
-				}
+			var messageEmbeds []*discordgo.MessageEmbed
 
-				defer resp.Body.Close()
+			if messageEmbed != nil {
+				messageEmbeds = []*discordgo.MessageEmbed{messageEmbed}
 			}
 
-			_, err := s.ChannelMessageSendComplex(guildConfig.WelcomeChannelId, &discordgo.MessageSend{
+			_, err = s.ChannelMessageSendComplex(guildConfig.GoodbyeChannelId, &discordgo.MessageSend{
 				Content: messageContent,
-				Files:   messageFiles,
+				Embeds:  messageEmbeds,
+				AllowedMentions: &discordgo.MessageAllowedMentions{
+					Parse: []discordgo.AllowedMentionType{},
+				},
 			})
 
 			if err != nil {
-				log.Printf("Failed to send welcome message in %v: %v", e.GuildID, err)
+				log.Printf("Failed to send goodbye message in %v: %v", e.GuildID, err)
 			}
 		}
 	}
 }
+
+// Audit log entries older than this are not attributed to a member removal
+const auditLogWindow = 15 * time.Second
+
+// Looks through the guild audit log for a kick or ban of the user that just left
+func memberRemovalReason(s *discordgo.Session, guildId, userId string) utils.GoodbyeReason {
+	// Audit log entries can lag slightly behind the gateway event
+	time.Sleep(2 * time.Second)
+
+	actions := []struct {
+		kind   string
+		action discordgo.AuditLogAction
+	}{
+		{utils.GoodbyeBan, discordgo.AuditLogActionMemberBanAdd},
+		{utils.GoodbyeKick, discordgo.AuditLogActionMemberKick},
+	}
+
+	for _, action := range actions {
+		auditLog, err := s.GuildAuditLog(guildId, "", "", int(action.action), 10)
+
+		if err != nil {
+			log.Printf("Failed to read audit log of %v, check bot permissions: %v", guildId, err)
+			break
+		}
+
+		for _, entry := range auditLog.AuditLogEntries {
+			if entry.TargetID != userId {
+				continue
+			}
+
+			created, err := discordgo.SnowflakeTimestamp(entry.ID)
+
+			if err != nil || time.Since(created) > auditLogWindow {
+				continue
+			}
+
+			reason := utils.GoodbyeReason{Kind: action.kind, Reason: entry.Reason}
+
+			for _, user := range auditLog.Users {
+				if user.ID == entry.UserID {
+					reason.Moderator = user
+				}
+			}
+
+			return reason
+		}
+	}
+
+	return utils.GoodbyeReason{Kind: utils.GoodbyeLeave}
+}
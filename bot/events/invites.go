@@ -0,0 +1,23 @@
+package events
+
+import (
+	"kodachi/utils"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Caches invite uses of every guild on startup and when the bot joins a guild
+func InviteCacheEventHandler(s *discordgo.Session, e *discordgo.GuildCreate) {
+	if err := utils.CacheGuildInvites(s, e.ID); err != nil {
+		log.Printf("Failed to cache invites of %v: %v", e.ID, err)
+	}
+}
+
+func InviteCreateEventHandler(s *discordgo.Session, e *discordgo.InviteCreate) {
+	utils.CacheInvite(e.GuildID, e.Invite)
+}
+
+func InviteDeleteEventHandler(s *discordgo.Session, e *discordgo.InviteDelete) {
+	utils.UncacheInvite(e.GuildID, e.Code)
+}
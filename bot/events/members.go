@@ -0,0 +1,45 @@
+package events
+
+import (
+	"kodachi/bot/models"
+	"kodachi/utils"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Records the invite user joined with, along with their account age for member stats
+func recordMemberJoin(db *gorm.DB, s *discordgo.Session, guildId string, user *discordgo.User) {
+	memberJoin := models.MemberJoin{GuildId: guildId, UserId: user.ID}
+
+	if created, err := discordgo.SnowflakeTimestamp(user.ID); err == nil {
+		memberJoin.AccountCreatedAt = created
+	}
+
+	code, inviterId, err := utils.FindUsedInvite(s, guildId)
+
+	if err != nil {
+		log.Printf("Failed to find the invite %v joined %v with: %v", user.ID, guildId, err)
+	} else {
+		memberJoin.InviteCode = code
+		memberJoin.InviterId = inviterId
+	}
+
+	result := db.Create(&memberJoin)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+	}
+}
+
+// Records members leaving for member stats
+func MemberLeaveEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+		result := db.Create(&models.MemberLeave{GuildId: e.GuildID, UserId: e.User.ID})
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package events
+
+import (
+	"errors"
+	"kodachi/bot/models"
+	"kodachi/utils"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Serializes mirroring, so that pin updates arriving together don't copy a message twice
+var pinsMirrorMutex sync.Mutex
+
+// How long after a pin its update event may arrive, older timestamps come from unpins
+const pinsMirrorWindow = time.Minute
+
+// Copies messages pinned with Discord's own pins to the pins channel, unpinning them if configured.
+// The event is sent for both pins and unpins, only a pin sets the last pin timestamp to now.
+// Pins made before mirroring was turned on are left alone.
+func PinsMirrorEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.ChannelPinsUpdate) {
+	return func(s *discordgo.Session, e *discordgo.ChannelPinsUpdate) {
+		if e.GuildID == "" || e.LastPinTimestamp == "" {
+			return
+		}
+
+		lastPin, err := time.Parse(time.RFC3339, e.LastPinTimestamp)
+
+		if err != nil {
+			log.Printf("Failed to parse last pin timestamp of %v: %v", e.ChannelID, err)
+			return
+		}
+
+		if time.Since(lastPin) > pinsMirrorWindow {
+			return
+		}
+
+		var guildConfig = models.Config{}
+
+		result := db.Where(&models.Config{GuildId: e.GuildID}).First(&guildConfig)
+
+		switch {
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			return
+		case result.Error != nil:
+			log.Print(result.Error)
+			return
+		}
+
+		if !guildConfig.PinsMirrorNative {
+			return
+		}
+
+		pinsChannelId, err := utils.PinsChannel(db, s, guildConfig, e.ChannelID)
+
+		switch {
+		case errors.Is(err, utils.ErrPinsFromPinsChannel):
+			return
+		case err != nil:
+			log.Print(err)
+			return
+		case pinsChannelId == "":
+			return
+		}
+
+		pinsMirrorMutex.Lock()
+		defer pinsMirrorMutex.Unlock()
+
+		messages, err := s.ChannelMessagesPinned(e.ChannelID)
+
+		if err != nil {
+			log.Printf("Failed to fetch pinned messages of %v: %v", e.ChannelID, err)
+			return
+		}
+
+		// Newest first, the message that was just pinned
+		if len(messages) == 0 {
+			return
+		}
+
+		message := messages[0]
+
+		var count int64
+
+		result = db.Model(&models.PinnedMessage{}).Where(&models.PinnedMessage{GuildId: e.GuildID, MessageId: message.ID}).Count(&count)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			return
+		case count > 0:
+			return
+		}
+
+		pinnedMessage, err := utils.PinMessage(db, s, guildConfig, pinsChannelId, message, nativePinners(s, e.GuildID)[message.ID], 0)
+
+		if err != nil {
+			log.Printf("Failed to mirror pinned message %v: %v", message.ID, err)
+			return
+		}
+
+		if guildConfig.PinsUnpinNative {
+			err := s.ChannelMessageUnpin(e.ChannelID, message.ID)
+
+			if err == nil {
+				return
+			}
+
+			log.Printf("Failed to unpin mirrored message %v: %v", message.ID, err)
+		}
+
+		// Kept pinned, so that unpinning the copy unpins it too
+		result = db.Model(pinnedMessage).Update("natively_pinned", true)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
+
+// Returns {"MessageID": Pinner} of recent pins from the audit log, empty if it can't be read
+func nativePinners(s *discordgo.Session, guildId string) map[string]*discordgo.User {
+	pinners := make(map[string]*discordgo.User)
+
+	auditLog, err := s.GuildAuditLog(guildId, "", "", int(discordgo.AuditLogActionMessagePin), 50)
+
+	if err != nil {
+		log.Printf("Failed to read pins from the audit log of %v: %v", guildId, err)
+		return pinners
+	}
+
+	users := make(map[string]*discordgo.User, len(auditLog.Users))
+	for _, user := range auditLog.Users {
+		users[user.ID] = user
+	}
+
+	for _, entry := range auditLog.AuditLogEntries {
+		if entry.Options == nil || users[entry.UserID] == nil {
+			continue
+		}
+
+		// Entries are newest first, keep the latest pinner of a message
+		if _, ok := pinners[entry.Options.MessageID]; !ok {
+			pinners[entry.Options.MessageID] = users[entry.UserID]
+		}
+	}
+
+	return pinners
+}
+
+// Returns the config of a guild if it keeps pinned copies in sync with their originals
+func pinsSyncConfig(db *gorm.DB, guildId string) (models.Config, bool) {
+	var guildConfig = models.Config{}
+
+	result := db.Where(&models.Config{GuildId: guildId}).First(&guildConfig)
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound):
+		return guildConfig, false
+	case result.Error != nil:
+		log.Print(result.Error)
+		return guildConfig, false
+	}
+
+	if guildConfig.StarboardEmoji == "" {
+		guildConfig.StarboardEmoji = defaultStarboardEmoji
+	}
+
+	return guildConfig, guildConfig.PinsSyncOriginals
+}
+
+// Returns the copies of messages, usually none. Looked up before the config since almost every message has none.
+func pinnedCopies(db *gorm.DB, guildId string, messageIds ...string) []models.PinnedMessage {
+	pinnedMessages := []models.PinnedMessage{}
+
+	if guildId == "" {
+		return pinnedMessages
+	}
+
+	result := db.Where(&models.PinnedMessage{GuildId: guildId}).Where("message_id IN ?", messageIds).Find(&pinnedMessages)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+	}
+
+	return pinnedMessages
+}
+
+// Edits the copies of an edited message to match it
+func PinsMessageUpdateEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.MessageUpdate) {
+	return func(s *discordgo.Session, e *discordgo.MessageUpdate) {
+		pinnedMessages := pinnedCopies(db, e.GuildID, e.ID)
+		if len(pinnedMessages) == 0 {
+			return
+		}
+
+		guildConfig, ok := pinsSyncConfig(db, e.GuildID)
+		if !ok {
+			return
+		}
+
+		// Updates may be partial, e.g. only embeds when a link preview loads
+		message, err := s.ChannelMessage(e.ChannelID, e.ID)
+
+		if err != nil {
+			log.Printf("Failed to fetch edited message %v: %v", e.ID, err)
+			return
+		}
+
+		for _, pinnedMessage := range pinnedMessages {
+			components := utils.PinnedMessageComponents(e.GuildID, message, pinnedMessage.Stars, guildConfig.StarboardEmoji)
+			embeds := utils.PinnedMessageEmbeds(message, pinnedMessage)
+
+			err := utils.EditPinnedMessage(s, pinnedMessage, &discordgo.WebhookEdit{
+				Content:    &message.Content,
+				Embeds:     &embeds,
+				Components: &components,
+				AllowedMentions: &discordgo.MessageAllowedMentions{
+					Parse: []discordgo.AllowedMentionType{},
+				},
+			})
+
+			if err != nil {
+				log.Printf("Failed to sync pinned copy of %v: %v", message.ID, err)
+				continue
+			}
+
+			// Keeps searches matching the current text
+			result := db.Model(&pinnedMessage).Update("content", utils.PinnedMessageContent(message))
+
+			if result.Error != nil {
+				log.Print(result.Error)
+			}
+		}
+	}
+}
+
+// Marks the copies of a deleted message, keeping them
+func PinsMessageDeleteEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.MessageDelete) {
+	return func(s *discordgo.Session, e *discordgo.MessageDelete) {
+		markPinsOriginalDeleted(db, s, e.GuildID, e.ID)
+	}
+}
+
+func PinsMessageDeleteBulkEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.MessageDeleteBulk) {
+	return func(s *discordgo.Session, e *discordgo.MessageDeleteBulk) {
+		markPinsOriginalDeleted(db, s, e.GuildID, e.Messages...)
+	}
+}
+
+func markPinsOriginalDeleted(db *gorm.DB, s *discordgo.Session, guildId string, messageIds ...string) {
+	pinnedMessages := pinnedCopies(db, guildId, messageIds...)
+	if len(pinnedMessages) == 0 {
+		return
+	}
+
+	guildConfig, ok := pinsSyncConfig(db, guildId)
+	if !ok {
+		return
+	}
+
+	for _, pinnedMessage := range pinnedMessages {
+		components := utils.DeletedPinnedMessageComponents(pinnedMessage, guildConfig.StarboardEmoji)
+
+		err := utils.EditPinnedMessage(s, pinnedMessage, &discordgo.WebhookEdit{
+			Components: &components,
+		})
+
+		if err != nil {
+			log.Printf("Failed to mark pinned copy of %v as deleted: %v", pinnedMessage.MessageId, err)
+		}
+
+		// Set even if the copy couldn't be edited, the original can't be jumped to either way
+		result := db.Model(&pinnedMessage).Update("original_deleted", true)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/utils"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type joinRaid struct {
+	joins []time.Time       // Joins within the raid window
+	batch []*discordgo.User // Members waiting for the batched welcome, nil outside of a raid
+
+	alertedAt time.Time
+}
+
+// Raids continuing after their first window are only alerted about again after this long
+const raidAlertCooldown = 10 * time.Minute
+
+// Recent joins, by guild
+var joinRaids = struct {
+	sync.Mutex
+	guilds map[string]*joinRaid
+}{guilds: map[string]*joinRaid{}}
+
+// Adds user to the batched welcome if more members than the guild's threshold joined within its window.
+// Returns false if user should be welcomed on their own.
+func batchRaidWelcome(s *discordgo.Session, guildConfig models.Config, user *discordgo.User) bool {
+	if guildConfig.RaidJoinThreshold == 0 {
+		return false
+	}
+
+	window := time.Duration(guildConfig.RaidJoinWindowSeconds) * time.Second
+
+	joinRaids.Lock()
+	defer joinRaids.Unlock()
+
+	raid, ok := joinRaids.guilds[guildConfig.GuildId]
+
+	if !ok {
+		raid = &joinRaid{}
+		joinRaids.guilds[guildConfig.GuildId] = raid
+	}
+
+	now := time.Now()
+	joins := []time.Time{}
+
+	for _, join := range raid.joins {
+		if now.Sub(join) < window {
+			joins = append(joins, join)
+		}
+	}
+
+	raid.joins = append(joins, now)
+
+	if raid.batch != nil {
+		raid.batch = append(raid.batch, user)
+		return true
+	}
+
+	if len(raid.joins) <= guildConfig.RaidJoinThreshold {
+		return false
+	}
+
+	// Raid started, welcome everyone who joins until the window is over at once
+	raid.batch = []*discordgo.User{user}
+
+	if now.Sub(raid.alertedAt) > raidAlertCooldown {
+		raid.alertedAt = now
+		go sendRaidAlert(s, guildConfig, len(raid.joins))
+	}
+
+	time.AfterFunc(window, func() {
+		joinRaids.Lock()
+		batch := raid.batch
+		raid.batch = nil
+		joinRaids.Unlock()
+
+		sendBatchedWelcome(s, guildConfig, batch)
+	})
+
+	return true
+}
+
+// Welcomes a raid's members in the welcome channel, DM welcomes are skipped during raids
+func sendBatchedWelcome(s *discordgo.Session, guildConfig models.Config, batch []*discordgo.User) {
+	if guildConfig.WelcomeChannelId == "" || guildConfig.WelcomeDelivery == utils.WelcomeDeliveryDM {
+		return
+	}
+
+	mentions := make([]string, len(batch))
+
+	for index, user := range batch {
+		mentions[index] = fmt.Sprintf("<@%s>", user.ID)
+	}
+
+	header := fmt.Sprintf("Welcome to our %v new members!", len(batch))
+	chunks := utils.ChunkLines(mentions, ", ", 2000-len(header)-1)
+
+	for index, chunk := range chunks {
+		if index == 0 {
+			chunk = header + "\n" + chunk
+		}
+
+		// Members aren't pinged so that a raid doesn't turn into mass mentions
+		_, err := s.ChannelMessageSendComplex(guildConfig.WelcomeChannelId, &discordgo.MessageSend{
+			Content:         chunk,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+
+		if err != nil {
+			log.Printf("Failed to send batched welcome message in %v: %v", guildConfig.GuildId, err)
+			return
+		}
+	}
+}
+
+func sendRaidAlert(s *discordgo.Session, guildConfig models.Config, joins int) {
+	if guildConfig.RaidAlertChannelId == "" {
+		return
+	}
+
+	_, err := s.ChannelMessageSendComplex(guildConfig.RaidAlertChannelId, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Join raid detected",
+				Description: fmt.Sprintf("%v members joined within %v seconds, their welcomes are batched until it calms down.", joins, guildConfig.RaidJoinWindowSeconds),
+				Color:       0xED4245,
+				Timestamp:   time.Now().Format(time.RFC3339),
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Lockdown",
+						Style:    discordgo.DangerButton,
+						CustomID: utils.CustomID("raid_lockdown"),
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		log.Printf("Failed to send raid alert in %v: %v", guildConfig.GuildId, err)
+	}
+}
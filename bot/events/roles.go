@@ -0,0 +1,60 @@
+package events
+
+import (
+	"errors"
+	"kodachi/bot/models"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+func AutoRolesMemberAddEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
+		// Roles can't be given before the member accepts the server rules, PendingMemberUpdateEventHandler gives them after
+		if e.Pending {
+			return
+		}
+
+		scheduleAutoRoles(db, s, e.GuildID, e.User)
+	}
+}
+
+// Gives the configured auto roles to user after the configured delay
+func scheduleAutoRoles(db *gorm.DB, s *discordgo.Session, guildId string, user *discordgo.User) {
+	var guildConfig = models.Config{}
+
+	result := db.Where(&models.Config{GuildId: guildId}).First(&guildConfig)
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound):
+		return
+	case result.Error != nil:
+		log.Print(result.Error)
+		return
+	}
+
+	autoRoles := []models.AutoRole{}
+
+	result = db.Where(&models.AutoRole{GuildId: guildId}).Where("for_bots = ?", user.Bot).Find(&autoRoles)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+		return
+	}
+
+	if len(autoRoles) == 0 {
+		return
+	}
+
+	time.AfterFunc(time.Duration(guildConfig.AutoRoleDelaySeconds)*time.Second, func() {
+		for _, autoRole := range autoRoles {
+			err := s.GuildMemberRoleAdd(guildId, user.ID, autoRole.RoleId)
+
+			if err != nil {
+				log.Printf("Failed to give auto role %v to %v in %v: %v", autoRole.RoleId, user.ID, guildId, err)
+			}
+		}
+	})
+}
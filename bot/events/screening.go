@@ -0,0 +1,73 @@
+package events
+
+import (
+	"errors"
+	"kodachi/bot/models"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Records members that join before passing membership screening, so that it survives restarts
+func PendingMemberAddEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
+		if !e.Pending {
+			return
+		}
+
+		pendingMember := models.PendingMember{GuildId: e.GuildID, UserId: e.User.ID}
+
+		result := db.Where(&pendingMember).FirstOrCreate(&pendingMember)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
+
+// Gives auto roles, and the welcome if it was deferred, once a pending member passes membership screening
+func PendingMemberUpdateEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberUpdate) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberUpdate) {
+		if e.Pending {
+			return
+		}
+
+		result := db.Where(&models.PendingMember{GuildId: e.GuildID, UserId: e.User.ID}).Delete(&models.PendingMember{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			return
+		// Not a member that was pending, e.g. a nickname or role change
+		case result.RowsAffected == 0:
+			return
+		}
+
+		scheduleAutoRoles(db, s, e.GuildID, e.User)
+
+		var guildConfig = models.Config{}
+
+		result = db.Where(&models.Config{GuildId: e.GuildID}).First(&guildConfig)
+
+		switch {
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			return
+		case result.Error != nil:
+			log.Print(result.Error)
+		case guildConfig.WelcomeAfterScreening:
+			welcomeMember(db, s, guildConfig, e.User)
+		}
+	}
+}
+
+// Forgets members that leave before passing membership screening
+func PendingMemberRemoveEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+	return func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+		result := db.Where(&models.PendingMember{GuildId: e.GuildID, UserId: e.User.ID}).Delete(&models.PendingMember{})
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+	}
+}
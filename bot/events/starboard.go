@@ -0,0 +1,160 @@
+package events
+
+import (
+	"errors"
+	"hash/fnv"
+	"kodachi/bot/models"
+	"kodachi/utils"
+	"log"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+const defaultStarboardEmoji = "⭐"
+
+// Locks picked by message ID, so that reactions arriving together don't pin a message twice
+var starboardLocks [64]sync.Mutex
+
+func starboardLock(messageId string) *sync.Mutex {
+	hash := fnv.New32a()
+	hash.Write([]byte(messageId))
+
+	return &starboardLocks[hash.Sum32()%uint32(len(starboardLocks))]
+}
+
+func StarboardReactionAddEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.MessageReactionAdd) {
+	return func(s *discordgo.Session, e *discordgo.MessageReactionAdd) {
+		updateStarboard(db, s, e.MessageReaction)
+	}
+}
+
+func StarboardReactionRemoveEventHandler(db *gorm.DB) func(s *discordgo.Session, e *discordgo.MessageReactionRemove) {
+	return func(s *discordgo.Session, e *discordgo.MessageReactionRemove) {
+		updateStarboard(db, s, e.MessageReaction)
+	}
+}
+
+// Pins the reacted message once it has enough stars, or updates the star count of its copy
+func updateStarboard(db *gorm.DB, s *discordgo.Session, reaction *discordgo.MessageReaction) {
+	if reaction.GuildID == "" {
+		return
+	}
+
+	var guildConfig = models.Config{}
+
+	result := db.Where(&models.Config{GuildId: reaction.GuildID}).First(&guildConfig)
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound):
+		return
+	case result.Error != nil:
+		log.Print(result.Error)
+		return
+	}
+
+	if guildConfig.StarboardEmoji == "" {
+		guildConfig.StarboardEmoji = defaultStarboardEmoji
+	}
+
+	if guildConfig.StarboardThreshold == 0 || reaction.Emoji.APIName() != guildConfig.StarboardEmoji {
+		return
+	}
+
+	// Stars on the copies themselves aren't counted
+	pinsChannelId, err := utils.PinsChannel(db, s, guildConfig, reaction.ChannelID)
+
+	switch {
+	case errors.Is(err, utils.ErrPinsFromPinsChannel):
+		return
+	case err != nil:
+		log.Print(err)
+		return
+	case pinsChannelId == "":
+		return
+	}
+
+	lock := starboardLock(reaction.MessageID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	message, err := s.ChannelMessage(reaction.ChannelID, reaction.MessageID)
+
+	if err != nil {
+		log.Printf("Failed to fetch starred message %v: %v", reaction.MessageID, err)
+		return
+	}
+
+	stars, err := countStars(s, guildConfig, message)
+
+	if err != nil {
+		log.Printf("Failed to count stars of %v: %v", message.ID, err)
+		return
+	}
+
+	var pinnedMessage models.PinnedMessage
+
+	result = db.Where(&models.PinnedMessage{GuildId: reaction.GuildID, MessageId: message.ID}).First(&pinnedMessage)
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound):
+		if stars < guildConfig.StarboardThreshold {
+			return
+		}
+
+		if _, err := utils.PinMessage(db, s, guildConfig, pinsChannelId, message, nil, stars); err != nil {
+			log.Printf("Failed to pin starred message %v: %v", message.ID, err)
+		}
+
+	case result.Error != nil:
+		log.Print(result.Error)
+
+	// Already pinned, keep its copy even if it drops below the threshold
+	case pinnedMessage.Stars != stars:
+		result := db.Model(&pinnedMessage).Update("stars", stars)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			return
+		}
+
+		components := utils.PinnedMessageComponents(reaction.GuildID, message, stars, guildConfig.StarboardEmoji)
+
+		err := utils.EditPinnedMessage(s, pinnedMessage, &discordgo.WebhookEdit{
+			Components: &components,
+		})
+
+		if err != nil {
+			log.Printf("Failed to update star count of %v: %v", message.ID, err)
+		}
+	}
+}
+
+// Counts the members who starred message, leaving out bots and the author unless self stars are allowed
+func countStars(s *discordgo.Session, guildConfig models.Config, message *discordgo.Message) (int, error) {
+	stars := 0
+	after := ""
+
+	for {
+		users, err := s.MessageReactions(message.ChannelID, message.ID, guildConfig.StarboardEmoji, 100, "", after)
+
+		if err != nil {
+			return 0, err
+		}
+
+		for _, user := range users {
+			if user.Bot || (user.ID == message.Author.ID && !guildConfig.StarboardSelfStar) {
+				continue
+			}
+
+			stars++
+		}
+
+		if len(users) < 100 {
+			return stars, nil
+		}
+
+		after = users[len(users)-1].ID
+	}
+}
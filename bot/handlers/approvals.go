@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/utils"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Returns whether the member of an interaction can pin without approval, as a moderator or with a pin role.
+// Everyone can until the guild adds a pin role or an approval channel.
+func canPinDirectly(db *gorm.DB, i *discordgo.InteractionCreate, config models.Config) (bool, error) {
+	canReview, err := canReviewPinRequests(db, i)
+
+	if err != nil || canReview || config.PinsApprovalChannelId != "" {
+		return canReview, err
+	}
+
+	var count int64
+
+	result := db.Model(&models.PinRole{}).Where(&models.PinRole{GuildId: i.GuildID}).Count(&count)
+
+	return count == 0, result.Error
+}
+
+// Returns whether the member of an interaction is a moderator or has a pin role, regardless of whether pinning is restricted
+func canReviewPinRequests(db *gorm.DB, i *discordgo.InteractionCreate) (bool, error) {
+	if i.Member.Permissions&discordgo.PermissionManageMessages != 0 {
+		return true, nil
+	}
+
+	if len(i.Member.Roles) == 0 {
+		return false, nil
+	}
+
+	var count int64
+
+	result := db.Model(&models.PinRole{}).Where(&models.PinRole{GuildId: i.GuildID}).Where("role_id IN ?", i.Member.Roles).Count(&count)
+
+	return count > 0, result.Error
+}
+
+// Sends a pin request for message to the approval channel of the guild
+func requestPin(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, config models.Config, message *discordgo.Message) {
+	var count int64
+
+	result := db.Model(&models.PinRequest{}).Where(&models.PinRequest{GuildId: i.GuildID, MessageId: message.ID}).Count(&count)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	case count > 0:
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("This message is already awaiting approval."))
+		return
+	}
+
+	pinRequest := models.PinRequest{
+		GuildId:     i.GuildID,
+		ChannelId:   message.ChannelID,
+		MessageId:   message.ID,
+		RequesterId: i.Member.User.ID,
+	}
+
+	if result := db.Create(&pinRequest); result.Error != nil {
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	content := utils.PinnedMessageContent(message)
+	if content == "" {
+		content = "*No text*"
+	}
+
+	requestId := strconv.FormatUint(uint64(pinRequest.ID), 10)
+
+	_, err := s.ChannelMessageSendComplex(config.PinsApprovalChannelId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> requested to pin a message from <#%s>.", pinRequest.RequesterId, pinRequest.ChannelId),
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Author: &discordgo.MessageEmbedAuthor{
+					Name:    message.Author.Username,
+					IconURL: message.Author.AvatarURL(""),
+				},
+				Description: utils.Truncate(content, 4096),
+				Timestamp:   message.Timestamp.Format(time.RFC3339),
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					pinRequestJumpButton(pinRequest),
+					discordgo.Button{
+						Label:    "Approve",
+						Style:    discordgo.SuccessButton,
+						CustomID: utils.CustomID("pin_approve", requestId),
+					},
+					discordgo.Button{
+						Label:    "Reject",
+						Style:    discordgo.DangerButton,
+						CustomID: utils.CustomID("pin_reject", requestId),
+					},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	if err != nil {
+		log.Printf("Failed to send pin request %v: %v", pinRequest.ID, err)
+		db.Delete(&pinRequest)
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("Failed to send your pin request to the moderators, please try again."))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, responses.Ephemeral("Your pin request was sent to the moderators, you'll be told once it's reviewed."))
+}
+
+func pinRequestJumpButton(pinRequest models.PinRequest) discordgo.Button {
+	return discordgo.Button{
+		Label: "Jump",
+		Style: discordgo.LinkButton,
+		URL:   utils.MessageURL(pinRequest.GuildId, pinRequest.ChannelId, pinRequest.MessageId),
+	}
+}
+
+func pinApproveComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		reviewPinRequest(db, s, i, true)
+	}
+}
+
+func pinRejectComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		reviewPinRequest(db, s, i, false)
+	}
+}
+
+func reviewPinRequest(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, approve bool) {
+	canReview, err := canReviewPinRequests(db, i)
+
+	switch {
+	case err != nil:
+		log.Print(err)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	case !canReview:
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("Only moderators and members with a pin role can review pin requests."))
+		return
+	}
+
+	_, args := utils.ParseCustomID(i.MessageComponentData().CustomID)
+
+	if len(args) == 0 {
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	requestId, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	var pinRequest models.PinRequest
+
+	result := db.Where(&models.PinRequest{GuildId: i.GuildID}).First(&pinRequest, requestId)
+
+	// Deleted before reviewing, so that two moderators can't review the same request
+	if result.Error == nil {
+		result = db.Delete(&pinRequest)
+
+		if result.Error == nil && result.RowsAffected == 0 {
+			result.Error = gorm.ErrRecordNotFound
+		}
+	}
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound):
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("This pin request was already reviewed."))
+		return
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	reviewer := i.Member.User
+
+	if !approve {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:         fmt.Sprintf("<@%s>'s pin request was rejected by <@%s>.", pinRequest.RequesterId, reviewer.ID),
+				Components:      pinRequestReviewedComponents(pinRequest),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+
+		notifyPinRequester(s, pinRequest, fmt.Sprintf("Your request to pin a message in <#%s> was rejected by the moderators.", pinRequest.ChannelId))
+		return
+	}
+
+	// Downloading and uploading attachments can take longer than the 3 seconds to respond
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	pinnedMessage, err := approvePinRequest(db, s, pinRequest)
+
+	if errors.Is(err, errPinRequestMessageMissing) {
+		content := fmt.Sprintf("<@%s>'s pin request was closed, the message no longer exists.", pinRequest.RequesterId)
+		components := []discordgo.MessageComponent{}
+
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:         &content,
+			Components:      &components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		return
+	}
+
+	if err != nil {
+		// Kept for another try
+		db.Unscoped().Model(&pinRequest).Update("deleted_at", nil)
+
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: err.Error(),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	pinnedURL := utils.MessageURL(pinnedMessage.GuildId, pinnedMessage.PinsChannelId, pinnedMessage.WebhookMessageId)
+
+	content := fmt.Sprintf("<@%s>'s pin request was approved by <@%s>: %s", pinRequest.RequesterId, reviewer.ID, pinnedURL)
+	components := pinRequestReviewedComponents(pinRequest)
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         &content,
+		Components:      &components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	notifyPinRequester(s, pinRequest, fmt.Sprintf("Your request to pin a message in <#%s> was approved: %s", pinRequest.ChannelId, pinnedURL))
+}
+
+var errPinRequestMessageMissing = errors.New("The requested message no longer exists.")
+
+// Pins the message of a request as its requester, or returns its existing copy
+func approvePinRequest(db *gorm.DB, s *discordgo.Session, pinRequest models.PinRequest) (*models.PinnedMessage, error) {
+	var config = models.Config{GuildId: pinRequest.GuildId}
+
+	result := db.Where(&config).First(&config)
+
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		log.Print(result.Error)
+		return nil, errors.New(responses.GenericErrorResponse.Data.Content)
+	}
+
+	var pinnedMessage models.PinnedMessage
+
+	result = db.Where(&models.PinnedMessage{GuildId: pinRequest.GuildId, MessageId: pinRequest.MessageId}).Limit(1).Find(&pinnedMessage)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		return nil, errors.New(responses.GenericErrorResponse.Data.Content)
+
+	// Pinned by someone else meanwhile
+	case result.RowsAffected > 0:
+		return &pinnedMessage, nil
+	}
+
+	pinsChannelId, err := utils.PinsChannel(db, s, config, pinRequest.ChannelId)
+
+	switch {
+	case errors.Is(err, utils.ErrPinsFromPinsChannel):
+		return nil, err
+	case err != nil:
+		log.Print(err)
+		return nil, errors.New(responses.GenericErrorResponse.Data.Content)
+	case pinsChannelId == "":
+		return nil, errors.New(responses.NoPinsChannelConfigured.Data.Content)
+	}
+
+	message, err := s.ChannelMessage(pinRequest.ChannelId, pinRequest.MessageId)
+
+	if err != nil {
+		return nil, errPinRequestMessageMissing
+	}
+
+	requester, err := s.User(pinRequest.RequesterId)
+
+	if err != nil {
+		log.Printf("Failed to fetch pin requester %v: %v", pinRequest.RequesterId, err)
+		return nil, errors.New(responses.GenericErrorResponse.Data.Content)
+	}
+
+	return utils.PinMessage(db, s, config, pinsChannelId, message, requester, 0)
+}
+
+// Buttons of a reviewed pin request, without Approve and Reject
+func pinRequestReviewedComponents(pinRequest models.PinRequest) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{pinRequestJumpButton(pinRequest)},
+		},
+	}
+}
+
+func notifyPinRequester(s *discordgo.Session, pinRequest models.PinRequest, content string) {
+	err := utils.SendDM(s, pinRequest.RequesterId, &discordgo.MessageSend{Content: content})
+
+	if err != nil {
+		log.Printf("Failed to notify pin requester %v: %v", pinRequest.RequesterId, err)
+	}
+}
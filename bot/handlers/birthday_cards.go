@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/utils"
+	"log"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Opens the wish modal when a member presses "Sign the card"
+func birthdayCardSignComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		_, args := utils.ParseCustomID(i.MessageComponentData().CustomID)
+
+		card, ok := findOpenBirthdayCard(db, s, i, args)
+		if !ok {
+			return
+		}
+
+		if card.UserId == i.Member.User.ID {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("You can't sign your own birthday card."))
+			return
+		}
+
+		// Prefill the modal with an existing wish so it can be edited
+		var wish = models.BirthdayWish{CardId: card.ID, AuthorId: i.Member.User.ID}
+
+		result := db.Where(&wish).First(&wish)
+
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID: utils.CustomID("birthday_card_wish", strconv.FormatUint(uint64(card.ID), 10)),
+				Title:    "Sign the birthday card",
+				Components: []discordgo.MessageComponent{
+					textInputRow(discordgo.TextInput{
+						CustomID:    "wish",
+						Label:       "Your wish",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "Happy birthday! 🎉",
+						Value:       wish.Message,
+						Required:    true,
+						MaxLength:   300,
+					}),
+				},
+			},
+		})
+
+		if err != nil {
+			log.Printf("Failed to open birthday card modal: %v", err)
+		}
+	}
+}
+
+// Stores the wish submitted through the birthday card modal
+func birthdayCardWishModalHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		data := i.ModalSubmitData()
+		_, args := utils.ParseCustomID(data.CustomID)
+
+		card, ok := findOpenBirthdayCard(db, s, i, args)
+		if !ok {
+			return
+		}
+
+		author := i.Member.User
+		authorName := author.Username
+
+		if i.Member.Nick != "" {
+			authorName = i.Member.Nick
+		}
+
+		var wish = models.BirthdayWish{CardId: card.ID, AuthorId: author.ID}
+
+		result := db.Where(&wish).Assign(models.BirthdayWish{
+			AuthorName: authorName,
+			Message:    utils.ModalValues(data)["wish"],
+		}).FirstOrCreate(&wish)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Your wish has been added to <@%s>'s birthday card.", card.UserId)))
+		}
+	}
+}
+
+// Looks up the card referenced by a custom ID, responding to the interaction if it can't be signed
+func findOpenBirthdayCard(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) (models.BirthdayCard, bool) {
+	var card models.BirthdayCard
+
+	if len(args) != 1 {
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return card, false
+	}
+
+	cardId, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return card, false
+	}
+
+	result := db.First(&card, cardId)
+
+	switch {
+	case errors.Is(result.Error, gorm.ErrRecordNotFound) || card.Delivered:
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("This birthday card is no longer accepting wishes."))
+		return card, false
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return card, false
+	}
+
+	return card, true
+}
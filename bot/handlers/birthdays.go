@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/packages/templates"
+	"kodachi/utils"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+func birthdayTemplateHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	var author *discordgo.User
+
+	if i.User != nil {
+		author = i.User
+	} else {
+		author = i.Member.User
+	}
+
+	// Personal templates are keyed by author, server templates by guild
+	birthdayTemplate := models.BirthdayTemplate{AuthorId: author.ID}
+
+	if option, ok := subCommandOptionMap["scope"]; ok && option.StringValue() == "server" {
+		if i.Member == nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Server templates can only be managed from a server."))
+			return
+		}
+
+		if i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("You need the Manage Server permission to change this server's template."))
+			return
+		}
+
+		birthdayTemplate = models.BirthdayTemplate{GuildId: i.GuildID}
+	}
+
+	switch subCommand.Name {
+	case "set":
+		text := subCommandOptionMap["template"].StringValue()
+
+		if _, err := templates.Parse(text, utils.BirthdayTemplateVariables); err != nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Invalid template: %v", err)))
+			return
+		}
+
+		result := db.Where(&birthdayTemplate).Assign(models.BirthdayTemplate{Template: text}).FirstOrCreate(&birthdayTemplate)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully updated birthday template.",
+				},
+			})
+		}
+
+	case "preview":
+		var text string
+
+		if option, ok := subCommandOptionMap["template"]; ok {
+			text = option.StringValue()
+		} else {
+			var err error
+
+			text, err = utils.BirthdayTemplate(db, author.ID, i.GuildID)
+
+			if err != nil {
+				log.Print(err)
+				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+				return
+			}
+		}
+
+		template, err := templates.Parse(text, utils.BirthdayTemplateVariables)
+
+		if err != nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Invalid template: %v", err)))
+			return
+		}
+
+		current := time.Now().UTC()
+
+		// Preview with the author's next birthday, or with themselves if they have no entries
+		sample := models.Birthday{
+			UserId:     author.ID,
+			Name:       author.Username,
+			BirthMonth: int64(current.Month()),
+			BirthDay:   int64(current.Day()),
+		}
+
+		userBirthdays := []models.Birthday{}
+
+		result := db.Where(&models.Birthday{AuthorId: author.ID}).Find(&userBirthdays)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+
+		if len(userBirthdays) > 0 {
+			sort.SliceStable(userBirthdays, func(i, j int) bool {
+				return utils.NextDate(userBirthdays[i].BirthMonth, userBirthdays[i].BirthDay, current).Before(utils.NextDate(userBirthdays[j].BirthMonth, userBirthdays[j].BirthDay, current))
+			})
+
+			sample = userBirthdays[0]
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: template.Render(utils.BirthdayTemplateValues(sample, current)),
+				Flags:   discordgo.MessageFlagsEphemeral,
+				AllowedMentions: &discordgo.MessageAllowedMentions{
+					Parse: []discordgo.AllowedMentionType{},
+				},
+			},
+		})
+
+	case "reset":
+		result := db.Where(&birthdayTemplate).Delete(&models.BirthdayTemplate{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully reset birthday template.",
+				},
+			})
+		}
+	}
+}
+
+// Resolves the user of "birthday add" from either the user picker or a raw ID, responding to the interaction if neither is usable
+func resolveBirthdayUser(s *discordgo.Session, i *discordgo.InteractionCreate, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) (*discordgo.User, bool) {
+	if option, ok := optionMap["user"]; ok {
+		userId := option.UserValue(nil).ID
+
+		if user, ok := i.ApplicationCommandData().Resolved.Users[userId]; ok {
+			return user, true
+		}
+
+		return option.UserValue(s), true
+	}
+
+	option, ok := optionMap["user_id"]
+	if !ok {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Please provide either a user or a user ID.",
+			},
+		})
+		return nil, false
+	}
+
+	userId, ok := utils.ParseUserID(option.StringValue())
+	if !ok {
+		s.InteractionRespond(i.Interaction, responses.InvalidUserId)
+		return nil, false
+	}
+
+	user, err := s.User(userId)
+	if err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Could not find a Discord user with ID %s.", userId),
+			},
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// Returns the user's nickname in the guild if they have one, their username otherwise
+func birthdayDisplayName(s *discordgo.Session, guildId string, user *discordgo.User) string {
+	if guildId != "" {
+		member, err := s.State.Member(guildId, user.ID)
+
+		if err != nil {
+			member, err = s.GuildMember(guildId, user.ID)
+		}
+
+		if err == nil && member.Nick != "" {
+			return member.Nick
+		}
+	}
+
+	return user.Username
+}
+
+// Suggests the author's existing entries for "birthday update" and "birthday delete"
+func birthdayAutocompleteHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := i.ApplicationCommandData().Options
+
+		var query string
+
+		for _, opt := range options[0].Options {
+			if opt.Focused {
+				query = strings.ToLower(opt.StringValue())
+			}
+		}
+
+		var authorId string
+
+		if i.User != nil {
+			authorId = i.User.ID
+		} else {
+			authorId = i.Member.User.ID
+		}
+
+		userBirthdays := []models.Birthday{}
+
+		result := db.Where(&models.Birthday{AuthorId: authorId}).Order("name").Find(&userBirthdays)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+		}
+
+		choices := []*discordgo.ApplicationCommandOptionChoice{}
+
+		for _, birthday := range userBirthdays {
+			if !strings.Contains(strings.ToLower(birthday.Name), query) && !strings.Contains(birthday.UserId, query) {
+				continue
+			}
+
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				// Choice names are capped at 100 characters
+				Name:  utils.Truncate(fmt.Sprintf("%s (%s)", birthday.Name, birthday.UserId), 100),
+				Value: birthday.UserId,
+			})
+
+			// Discord shows at most 25 choices
+			if len(choices) == 25 {
+				break
+			}
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: choices,
+			},
+		})
+
+		if err != nil {
+			log.Printf("Failed to respond to birthday autocomplete: %v", err)
+		}
+	}
+}
@@ -8,8 +8,6 @@ import (
 	"kodachi/packages/trees"
 	"kodachi/utils"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -21,18 +19,62 @@ import (
 
 func InteractionCreateHandler(db *gorm.DB) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	var commandHandlers = map[string]CommandHandler{
-		"welcome":     welcomeCommandHandler(db),
-		"config":      configCommandHandler(db),
-		"birthday":    birthdayCommandHandler(db),
-		"tree":        treeCommandHandler(db),
-		"Pin Message": pinCommandHandler(db),
+		"welcome":       welcomeCommandHandler(db),
+		"config":        configCommandHandler(db),
+		"birthday":      birthdayCommandHandler(db),
+		"tree":          treeCommandHandler(db),
+		"invites":       invitesCommandHandler(db),
+		"stats":         statsCommandHandler(db),
+		"pins":          pinsCommandHandler(db),
+		"Pin Message":   pinCommandHandler(db),
+		"Unpin Message": unpinCommandHandler(db),
+	}
+
+	// Keyed by command name
+	var autocompleteHandlers = map[string]CommandHandler{
+		"birthday": birthdayAutocompleteHandler(db),
+		"config":   configAutocompleteHandler(db),
+	}
+
+	// Keyed by the custom ID prefix, see utils.ParseCustomID
+	var componentHandlers = map[string]CommandHandler{
+		"birthday_card_sign": birthdayCardSignComponentHandler(db),
+		"raid_lockdown":      raidLockdownComponentHandler(db),
+		"raid_lift":          raidLiftComponentHandler(db),
+		"pins_page":          pinsPageComponentHandler(db),
+		"pin_approve":        pinApproveComponentHandler(db),
+		"pin_reject":         pinRejectComponentHandler(db),
+	}
+
+	var modalHandlers = map[string]CommandHandler{
+		"birthday_card_wish": birthdayCardWishModalHandler(db),
+		"config_embed":       configEmbedModalHandler(db),
 	}
 
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		// If command handler exists
-		if commandHandler, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			// Call with session and interaction
-			commandHandler(s, i)
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			// If command handler exists
+			if commandHandler, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
+				// Call with session and interaction
+				commandHandler(s, i)
+			}
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			if autocompleteHandler, ok := autocompleteHandlers[i.ApplicationCommandData().Name]; ok {
+				autocompleteHandler(s, i)
+			}
+		case discordgo.InteractionMessageComponent:
+			prefix, _ := utils.ParseCustomID(i.MessageComponentData().CustomID)
+
+			if componentHandler, ok := componentHandlers[prefix]; ok {
+				componentHandler(s, i)
+			}
+		case discordgo.InteractionModalSubmit:
+			prefix, _ := utils.ParseCustomID(i.ModalSubmitData().CustomID)
+
+			if modalHandler, ok := modalHandlers[prefix]; ok {
+				modalHandler(s, i)
+			}
 		}
 	}
 }
@@ -52,10 +94,10 @@ func welcomeCommandHandler(db *gorm.DB) CommandHandler {
 				testCommandOptionMap[opt.Name] = opt
 			}
 
-			userId := i.Member.User.ID
+			user := i.Member.User
 
 			if option, ok := testCommandOptionMap["user"]; ok {
-				userId = option.UserValue(nil).ID
+				user = i.ApplicationCommandData().Resolved.Users[option.UserValue(nil).ID]
 			}
 
 			var guildConfig = models.Config{}
@@ -68,41 +110,85 @@ func welcomeCommandHandler(db *gorm.DB) CommandHandler {
 				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
 
 			default:
-				messageContent := strings.ReplaceAll(guildConfig.WelcomeMessage, "<@USER_ID>", fmt.Sprintf("<@%s>", userId))
+				// Rendering the welcome card can take longer than an interaction response allows
+				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+				})
+
+				// Previews don't count towards recently used pool messages
+				welcomeMessage, err := utils.PickWelcomeMessage(db, guildConfig, false)
 
-				validAttachmentURL, err := url.ParseRequestURI(guildConfig.WelcomeMessageAttachmentURL)
 				if err != nil {
-					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-						Type: discordgo.InteractionResponseChannelMessageWithSource,
-						Data: &discordgo.InteractionResponseData{
-							Content: "Attachment url is invalid.",
-						},
+					log.Print(err)
+					content := responses.GenericErrorResponse.Data.Content
+					s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+						Content: &content,
 					})
 					return
 				}
 
-				resp, err := http.Get(validAttachmentURL.String())
+				guildConfig.WelcomeMessage = welcomeMessage
+
+				messageContent, messageEmbed, err := utils.RenderWelcome(db, s, guildConfig, user)
 
 				if err != nil {
-					log.Printf("An error occurred while fetching image: %v", err)
+					content := fmt.Sprintf("Welcome message is invalid: %v", err)
+					s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+						Content: &content,
+					})
+					return
 				}
 
-				defer resp.Body.Close()
+				messageEmbeds := []*discordgo.MessageEmbed{}
 
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: messageContent,
-						Files: []*discordgo.File{
-							{
-								ContentType: resp.Header.Get("Content-Type"),
-								Name:        "welcome.png",
-								Reader:      resp.Body,
-							},
-						},
-					},
+				if messageEmbed != nil {
+					messageEmbeds = append(messageEmbeds, messageEmbed)
+				}
+
+				var messageFiles []*discordgo.File
+
+				switch {
+				case guildConfig.WelcomeCardEnabled:
+					card, err := utils.WelcomeCard(db, s, guildConfig, user)
+
+					if err != nil {
+						log.Printf("An error occurred while rendering welcome card: %v", err)
+						content := "An error occurred while rendering welcome card."
+						s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+							Content: &content,
+						})
+						return
+					}
+
+					messageFiles = []*discordgo.File{card}
+
+				default:
+					attachment, err := utils.WelcomeAttachment(db, guildConfig)
+
+					if err != nil {
+						log.Printf("Failed to load welcome attachment of %v: %v", guildConfig.GuildId, err)
+						content := fmt.Sprintf("Failed to load the welcome attachment: %v", err)
+						s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+							Content: &content,
+						})
+						return
+					}
+
+					if attachment != nil {
+						messageFiles = []*discordgo.File{attachment}
+					}
+				}
+
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+					Content: &messageContent,
+					Embeds:  &messageEmbeds,
+					Files:   messageFiles,
 				})
 			}
+		case "test_goodbye":
+			welcomeTestGoodbyeHandler(db, s, i, options[0])
+		case "test_dm":
+			welcomeTestDMHandler(db, s, i)
 		}
 	}
 }
@@ -123,15 +209,67 @@ func configCommandHandler(db *gorm.DB) CommandHandler {
 				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
 
 			default:
+				var welcomeDMFailures int64
+
+				db.Model(&models.WelcomeDMFailure{}).Where(&models.WelcomeDMFailure{GuildId: i.GuildID}).Where("created_at > ?", time.Now().AddDate(0, 0, -7)).Count(&welcomeDMFailures)
+
+				welcomeAttachment := fmt.Sprintf("%s <%s>", config.WelcomeMessageAttachmentName, config.WelcomeMessageAttachmentURL)
+
+				if config.WelcomeMessageAttachmentRemoved != "" {
+					welcomeAttachment = fmt.Sprintf("removed, upload it again (%s)", config.WelcomeMessageAttachmentRemoved)
+				}
+
+				configLines := []string{
+					fmt.Sprintf("Welcome message: %s", config.WelcomeMessage),
+					fmt.Sprintf("Welcome message attachment: %s", welcomeAttachment),
+					fmt.Sprintf("Pins channel: %s", config.PinsChannelId),
+					fmt.Sprintf("Starboard: %v %s (self stars: %t)", config.StarboardThreshold, config.StarboardEmoji, config.StarboardSelfStar),
+					fmt.Sprintf("Mirror native pins: %t (unpin: %t)", config.PinsMirrorNative, config.PinsUnpinNative),
+					fmt.Sprintf("Sync pins with edits and deletions: %t", config.PinsSyncOriginals),
+					fmt.Sprintf("Send pins as their author: %t", config.PinsAsAuthor),
+					fmt.Sprintf("Pin approval channel: %s", config.PinsApprovalChannelId),
+					fmt.Sprintf("Welcome channel: %s", config.WelcomeChannelId),
+					fmt.Sprintf("Birthday channel: %s", config.BirthdayChannelId),
+					fmt.Sprintf("Welcome embed: %t", utils.WelcomeEmbedTemplate(config).IsSet()),
+					fmt.Sprintf("Welcome card: %t", config.WelcomeCardEnabled),
+					fmt.Sprintf("Welcome delivery: %s", config.WelcomeDelivery),
+					fmt.Sprintf("Welcome DM message: %s", config.WelcomeDMMessage),
+					fmt.Sprintf("Welcome after screening: %t", config.WelcomeAfterScreening),
+					fmt.Sprintf("Welcome DM failures (last 7 days): %v", welcomeDMFailures),
+					fmt.Sprintf("Auto role delay: %vs", config.AutoRoleDelaySeconds),
+					fmt.Sprintf("Raid threshold: %v joins in %vs", config.RaidJoinThreshold, config.RaidJoinWindowSeconds),
+					fmt.Sprintf("Raid alert channel: %s", config.RaidAlertChannelId),
+					fmt.Sprintf("Goodbye channel: %s", config.GoodbyeChannelId),
+					fmt.Sprintf("Goodbye message: %s", config.GoodbyeMessage),
+					fmt.Sprintf("Goodbye message (kick): %s", config.GoodbyeKickMessage),
+					fmt.Sprintf("Goodbye message (ban): %s", config.GoodbyeBanMessage),
+					fmt.Sprintf("Goodbye embed: %t", utils.GoodbyeEmbedTemplate(config).IsSet()),
+				}
+
 				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 					Type: discordgo.InteractionResponseChannelMessageWithSource,
 					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("Configuration for %s:\n\nWelcome message: %s\nWelcome message attachment: <%s>\nPins channel: %s\nWelcome channel: %s", i.GuildID, config.WelcomeMessage, config.WelcomeMessageAttachmentURL, config.PinsChannelId, config.WelcomeChannelId),
+						Content: fmt.Sprintf("Configuration for %s:\n\n%s", i.GuildID, strings.Join(configLines, "\n")),
 					},
 				})
 			}
+		case "welcome":
+			configWelcomeHandler(db, s, i, options[0].Options[0])
+		case "goodbye":
+			configGoodbyeHandler(db, s, i, options[0].Options[0])
+		case "welcome_messages":
+			configWelcomeMessagesHandler(db, s, i, options[0].Options[0])
+		case "auto_roles":
+			configAutoRolesHandler(db, s, i, options[0].Options[0])
+		case "raids":
+			configRaidsHandler(db, s, i, options[0].Options[0])
+		case "starboard":
+			configStarboardHandler(db, s, i, options[0].Options[0])
+		case "pins":
+			configPinsHandler(db, s, i, options[0].Options[0])
 		case "set":
 			var newGuildConfig = models.Config{GuildId: i.GuildID}
+			var configUpdate interface{} = &newGuildConfig
 
 			subCommandOptions := options[0].Options
 			subSubCommandOptions := subCommandOptions[0].Options
@@ -144,27 +282,46 @@ func configCommandHandler(db *gorm.DB) CommandHandler {
 			switch subCommandOptions[0].Name {
 
 			case "welcome_message":
-				newGuildConfig.WelcomeMessage = subSubCommandOptionMap["message"].StringValue()
-			case "welcome_message_attachment":
-				validAttachmentURL, err := url.ParseRequestURI(subSubCommandOptionMap["attachment_url"].StringValue())
-				if err != nil {
+				message := subSubCommandOptionMap["message"].StringValue()
+
+				if _, err := utils.ParseWelcomeTemplate(message); err != nil {
 					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
-							Content: "Please provide a valid url.",
+							Content: fmt.Sprintf("Invalid welcome message: %v", err),
 						},
 					})
 					return
 				}
 
-				newGuildConfig.WelcomeMessageAttachmentURL = validAttachmentURL.String()
+				newGuildConfig.WelcomeMessage = message
+			case "welcome_message_attachment":
+				configWelcomeAttachmentHandler(db, s, i, subSubCommandOptionMap)
+				return
 			case "pins_channel_id":
 				newGuildConfig.PinsChannelId = subSubCommandOptionMap["channel"].ChannelValue(s).ID
 			case "welcome_channel_id":
 				newGuildConfig.WelcomeChannelId = subSubCommandOptionMap["channel"].ChannelValue(s).ID
+			case "birthday_channel_id":
+				newGuildConfig.BirthdayChannelId = subSubCommandOptionMap["channel"].ChannelValue(s).ID
+			case "goodbye_channel_id":
+				newGuildConfig.GoodbyeChannelId = subSubCommandOptionMap["channel"].ChannelValue(s).ID
+			case "goodbye_message":
+				configGoodbyeMessageHandler(db, s, i, subSubCommandOptionMap)
+				return
+			case "welcome_dm_message":
+				configMessageHandler(db, s, i, "welcome_dm_message", subSubCommandOptionMap["message"].StringValue(), utils.ParseWelcomeTemplate)
+				return
+			case "welcome_delivery":
+				newGuildConfig.WelcomeDelivery = subSubCommandOptionMap["mode"].StringValue()
+			case "welcome_after_screening":
+				// Map so that disabling isn't skipped as a zero value
+				configUpdate = map[string]interface{}{
+					"welcome_after_screening": subSubCommandOptionMap["enabled"].BoolValue(),
+				}
 			}
 
-			result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(&newGuildConfig)
+			result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(configUpdate)
 
 			switch {
 			case result.Error != nil:
@@ -198,7 +355,12 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 
 		switch options[0].Name {
 		case "add":
-			birthdayUserId := subCommandOptionMap["user_id"].StringValue()
+			birthdayUser, ok := resolveBirthdayUser(s, i, subCommandOptionMap)
+			if !ok {
+				return
+			}
+
+			birthdayUserId := birthdayUser.ID
 
 			var authorId string
 
@@ -215,9 +377,19 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 			switch {
 			// Birthday does not exist, create it
 			case errors.Is(result.Error, gorm.ErrRecordNotFound):
-				userBirthday.Name = subCommandOptionMap["name"].StringValue()
+				userBirthday.Name = birthdayDisplayName(s, i.GuildID, birthdayUser)
+
+				if subCommandOptionMap["name"] != nil {
+					userBirthday.Name = subCommandOptionMap["name"].StringValue()
+				}
+
 				userBirthday.BirthDay = subCommandOptionMap["day"].IntValue()
 				userBirthday.BirthMonth = subCommandOptionMap["month"].IntValue()
+				userBirthday.GuildId = i.GuildID
+
+				if subCommandOptionMap["year"] != nil {
+					userBirthday.BirthYear = subCommandOptionMap["year"].IntValue()
+				}
 
 				result := db.Create(&userBirthday)
 
@@ -249,7 +421,11 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 			}
 
 		case "update":
-			birthdayUserId := subCommandOptionMap["user_id"].StringValue()
+			birthdayUserId, ok := utils.ParseUserID(subCommandOptionMap["user_id"].StringValue())
+			if !ok {
+				s.InteractionRespond(i.Interaction, responses.InvalidUserId)
+				return
+			}
 
 			var birthdayUpdate models.Birthday
 
@@ -265,6 +441,10 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 				birthdayUpdate.BirthMonth = subCommandOptionMap["month"].IntValue()
 			}
 
+			if subCommandOptionMap["year"] != nil {
+				birthdayUpdate.BirthYear = subCommandOptionMap["year"].IntValue()
+			}
+
 			var authorId string
 
 			if i.User != nil {
@@ -312,7 +492,11 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 			}
 
 		case "delete":
-			birthdayUserId := subCommandOptionMap["user_id"].StringValue()
+			birthdayUserId, ok := utils.ParseUserID(subCommandOptionMap["user_id"].StringValue())
+			if !ok {
+				s.InteractionRespond(i.Interaction, responses.InvalidUserId)
+				return
+			}
 
 			var authorId string
 
@@ -433,6 +617,8 @@ func birthdayCommandHandler(db *gorm.DB) CommandHandler {
 					})
 				}
 			}
+		case "template":
+			birthdayTemplateHandler(db, s, i, options[0].Options[0])
 		}
 	}
 }
@@ -457,124 +643,143 @@ func pinCommandHandler(db *gorm.DB) CommandHandler {
 
 		result := db.Where(&config).First(&config)
 
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		// Routed by the channel of the message, falling back to the pins channel of the guild
+		pinsChannelId, err := utils.PinsChannel(db, s, config, i.ChannelID)
+
 		switch {
+		case errors.Is(err, utils.ErrPinsFromPinsChannel):
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(err.Error()))
+		case err != nil:
+			log.Print(err)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
 		// Pins channel is not configured, inform user
-		case errors.Is(result.Error, gorm.ErrRecordNotFound) || config.PinsChannelId == "":
+		case pinsChannelId == "":
 			s.InteractionRespond(i.Interaction, responses.NoPinsChannelConfigured)
-		case result.Error != nil:
-			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
 
 		// Pins channel is configured, send message to it
 		default:
-			var usableWebhook *discordgo.Webhook
-			author := i.Member.User
+			var pinnedMessage models.PinnedMessage
 
-			_, err := s.Channel(config.PinsChannelId)
+			result := db.Where(&models.PinnedMessage{GuildId: i.GuildID, MessageId: message.ID}).Limit(1).Find(&pinnedMessage)
 
-			if err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "Configured pins channel does not exist.",
-					},
-				})
+			if result.Error != nil {
+				log.Print(result.Error)
+				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
 				return
 			}
 
-			webhooks, err := s.ChannelWebhooks(config.PinsChannelId)
-
-			if err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "Failed to get guild webhooks, check bot permissions.",
-					},
-				})
+			// Link to the existing copy instead of posting the message twice
+			if result.RowsAffected > 0 {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf(
+					"This message is already pinned: %s",
+					utils.MessageURL(i.GuildID, pinnedMessage.PinsChannelId, pinnedMessage.WebhookMessageId),
+				)))
 				return
 			}
 
-			exists := false
-
-			for _, webhook := range webhooks {
-				if webhook.ApplicationID == s.State.User.ID {
-					exists = true
+			canPin, err := canPinDirectly(db, i, config)
 
-					usableWebhook = webhook
-				}
+			if err != nil {
+				log.Print(err)
+				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+				return
 			}
 
-			if !exists {
-				// Create a webhook
-				usableWebhook, err = s.WebhookCreate(config.PinsChannelId, fmt.Sprintf("Pins [%s]", s.State.User.Username), "")
-
-				if err != nil {
-					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-						Type: discordgo.InteractionResponseChannelMessageWithSource,
-						Data: &discordgo.InteractionResponseData{
-							Content: "Failed to create a webhook, please create one yourself or check bot permissions.",
-						},
-					})
+			if !canPin {
+				// Members who can't pin ask the moderators to, if the guild takes requests
+				if config.PinsApprovalChannelId != "" {
+					requestPin(db, s, i, config, message)
 					return
 				}
+
+				s.InteractionRespond(i.Interaction, responses.Ephemeral("You don't have permission to pin messages."))
+				return
 			}
 
-			wait := false
+			// Downloading and uploading attachments can take longer than the 3 seconds to respond
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			})
 
-			buttonRow := discordgo.ActionsRow{
-				Components: []discordgo.MessageComponent{
-					discordgo.Button{
-						Label: "Jump",
-						Style: discordgo.LinkButton,
-						URL:   utils.MessageURL(i.GuildID, message.ChannelID, message.ID),
-					},
-				},
-			}
+			author := i.Member.User
 
-			messageFiles, err := utils.AttachmentsToFile(message.Attachments)
+			pinnedCopy, err := utils.PinMessage(db, s, config, pinsChannelId, message, author, 0)
 
 			if err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "Failed to download message attachment, please try again.",
-					},
-				})
+				content := err.Error()
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
 				return
 			}
 
-			_, err = s.WebhookExecute(usableWebhook.ID, usableWebhook.Token, wait, &discordgo.WebhookParams{
-				Username:   author.Username,
-				AvatarURL:  author.AvatarURL(""),
-				Content:    message.Content,
-				Embeds:     message.Embeds,
-				TTS:        message.TTS,
-				Files:      messageFiles,
-				Components: append(message.Components, buttonRow),
-				AllowedMentions: &discordgo.MessageAllowedMentions{
-					Parse: []discordgo.AllowedMentionType{},
-				},
-			})
+			content := fmt.Sprintf("<@%s> pinned a message from this channel. See all pinned messages <#%s>", author.ID, pinsChannelId)
+			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
 
-			if err != nil {
-				log.Printf("An error occurred while sending pin message: %v", err)
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "Failed to send the pinned message, please try again.",
-					},
-				})
-			} else {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("<@%s> pinned a message from this channel. See all pinned messages <#%s>", author.ID, config.PinsChannelId),
-					},
+			// Only the pinner is told which attachments couldn't be uploaded
+			if linked := utils.LinkedAttachments(message, *pinnedCopy); len(linked) > 0 {
+				names := make([]string, len(linked))
+				for index, attachment := range linked {
+					names[index] = attachment.Filename
+				}
+
+				s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+					Content: utils.Truncate(fmt.Sprintf(
+						"Pinned %v of %v attachments, these were too large for this server's upload limit or failed to download and were linked instead: %s",
+						len(message.Attachments)-len(linked), len(message.Attachments), strings.Join(names, ", "),
+					), 2000),
+					Flags: discordgo.MessageFlagsEphemeral,
 				})
 			}
 		}
 	}
 }
 
+// Removes the copy of a pinned message, targeting either the original or the copy
+func unpinCommandHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		messageId := i.ApplicationCommandData().TargetID
+
+		var pinnedMessage models.PinnedMessage
+
+		result := db.Where("guild_id = ? AND (message_id = ? OR webhook_message_id = ?)", i.GuildID, messageId, messageId).First(&pinnedMessage)
+
+		switch {
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("This message isn't pinned."))
+			return
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		if i.Member.User.ID != pinnedMessage.PinnerId && i.Member.Permissions&discordgo.PermissionManageMessages == 0 {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Only the member who pinned this message or moderators can unpin it."))
+			return
+		}
+
+		if err := utils.DeletePinnedMessage(db, s, pinnedMessage); err != nil {
+			log.Printf("Failed to unpin %v: %v", pinnedMessage.MessageId, err)
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Failed to delete the pinned message, please try again."))
+			return
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         fmt.Sprintf("<@%s> unpinned a message from <#%s>.", i.Member.User.ID, pinnedMessage.ChannelId),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	}
+}
+
 func treeCommandHandler(db *gorm.DB) CommandHandler {
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		options := i.ApplicationCommandData().Options
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+func invitesCommandHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := i.ApplicationCommandData().Options
+
+		switch options[0].Name {
+		case "leaderboard":
+			var inviters []struct {
+				InviterId string
+				Joins     int
+			}
+
+			result := db.Model(&models.MemberJoin{}).
+				Select("inviter_id, count(*) AS joins").
+				Where(&models.MemberJoin{GuildId: i.GuildID}).
+				Where("inviter_id <> ''").
+				Group("inviter_id").
+				Order("joins DESC").
+				Limit(10).
+				Scan(&inviters)
+
+			switch {
+			case result.Error != nil:
+				log.Print(result.Error)
+				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+			case len(inviters) == 0:
+				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: "No joins through invites have been tracked yet.",
+					},
+				})
+
+			default:
+				lines := make([]string, len(inviters))
+
+				for index, inviter := range inviters {
+					joins := "joins"
+					if inviter.Joins == 1 {
+						joins = "join"
+					}
+
+					lines[index] = fmt.Sprintf("**%v.** <@%s>: %v %s", index+1, inviter.InviterId, inviter.Joins, joins)
+				}
+
+				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Embeds: []*discordgo.MessageEmbed{
+							{
+								Title:       "Invite leaderboard",
+								Description: strings.Join(lines, "\n"),
+							},
+						},
+					},
+				})
+			}
+		}
+	}
+}
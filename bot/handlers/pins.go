@@ -0,0 +1,616 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+const pinsPageSize = 10
+
+func pinsCommandHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := i.ApplicationCommandData().Options
+
+		subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options[0].Options))
+		for _, opt := range options[0].Options {
+			subCommandOptionMap[opt.Name] = opt
+		}
+
+		page := 1
+		if option, ok := subCommandOptionMap["page"]; ok {
+			page = int(option.IntValue())
+		}
+
+		channelIds, err := visiblePinChannels(db, s, i)
+
+		if err != nil {
+			log.Print(err)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		switch options[0].Name {
+		case "list":
+			data, err := pinsPage(db, i.GuildID, channelIds, page, "")
+			pinsRespond(s, i, discordgo.InteractionResponseChannelMessageWithSource, data, err)
+
+		case "search":
+			data, err := pinsPage(db, i.GuildID, channelIds, page, subCommandOptionMap["query"].StringValue())
+			pinsRespond(s, i, discordgo.InteractionResponseChannelMessageWithSource, data, err)
+
+		case "random":
+			var pinnedMessage models.PinnedMessage
+
+			result := db.Where(&models.PinnedMessage{GuildId: i.GuildID}).Where("channel_id IN ?", channelIds).Order("RANDOM()").First(&pinnedMessage)
+
+			switch {
+			case errors.Is(result.Error, gorm.ErrRecordNotFound):
+				s.InteractionRespond(i.Interaction, responses.Ephemeral("No messages have been pinned yet."))
+
+			case result.Error != nil:
+				log.Print(result.Error)
+				s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+			default:
+				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Embeds: []*discordgo.MessageEmbed{pinnedMessageEmbed(pinnedMessage)},
+						Components: []discordgo.MessageComponent{
+							discordgo.ActionsRow{
+								Components: []discordgo.MessageComponent{
+									discordgo.Button{
+										Label: "Jump",
+										Style: discordgo.LinkButton,
+										URL:   utils.MessageURL(pinnedMessage.GuildId, pinnedMessage.ChannelId, pinnedMessage.MessageId),
+									},
+								},
+							},
+						},
+						Flags:           discordgo.MessageFlagsEphemeral,
+						AllowedMentions: &discordgo.MessageAllowedMentions{},
+					},
+				})
+			}
+		}
+	}
+}
+
+// Turns the page of a "/pins list" or "/pins search" response
+func pinsPageComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		_, args := utils.ParseCustomID(i.MessageComponentData().CustomID)
+
+		if len(args) == 0 {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		page, err := strconv.Atoi(args[0])
+		if err != nil {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		// The query may contain the custom ID separator
+		query := strings.Join(args[1:], ":")
+
+		// Pages are ephemeral, so this is the member who ran the command
+		channelIds, err := visiblePinChannels(db, s, i)
+
+		if err != nil {
+			log.Print(err)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		data, err := pinsPage(db, i.GuildID, channelIds, page, query)
+		pinsRespond(s, i, discordgo.InteractionResponseUpdateMessage, data, err)
+	}
+}
+
+func pinsRespond(s *discordgo.Session, i *discordgo.InteractionCreate, responseType discordgo.InteractionResponseType, data *discordgo.InteractionResponseData, err error) {
+	if err != nil {
+		log.Print(err)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: data,
+	})
+}
+
+// Returns the channels of the guild's pinned messages that the member of an interaction can view,
+// so that pins from private channels aren't shown to everyone
+func visiblePinChannels(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate) ([]string, error) {
+	channelIds := []string{}
+
+	result := db.Model(&models.PinnedMessage{}).Where(&models.PinnedMessage{GuildId: i.GuildID}).Distinct().Pluck("channel_id", &channelIds)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	guild, err := s.State.Guild(i.GuildID)
+
+	if err != nil {
+		guild, err = s.Guild(i.GuildID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	visible := []string{}
+
+	for _, channelId := range channelIds {
+		channel, err := pinChannel(s, channelId)
+
+		// Deleted channels can't be viewed
+		if err != nil {
+			continue
+		}
+
+		// Threads have the permissions of their parent channel
+		if channel.IsThread() {
+			if channel, err = pinChannel(s, channel.ParentID); err != nil {
+				continue
+			}
+		}
+
+		if utils.MemberChannelPermissions(guild, channel, i.Member)&discordgo.PermissionViewChannel != 0 {
+			visible = append(visible, channelId)
+		}
+	}
+
+	return visible, nil
+}
+
+// Returns a channel from state, fetching it if it isn't cached like archived threads
+func pinChannel(s *discordgo.Session, channelId string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelId); err == nil {
+		return channel, nil
+	}
+
+	return s.Channel(channelId)
+}
+
+// Renders a page of the guild's pinned messages from channelIds, only those containing query if it isn't empty.
+// Pages are ephemeral, channelIds are those the member can view.
+func pinsPage(db *gorm.DB, guildId string, channelIds []string, page int, query string) (*discordgo.InteractionResponseData, error) {
+	pinsQuery := db.Model(&models.PinnedMessage{}).Where(&models.PinnedMessage{GuildId: guildId}).Where("channel_id IN ?", channelIds)
+
+	if query != "" {
+		// Escaped so that % and _ in the query are matched literally
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+		pinsQuery = pinsQuery.Where("content ILIKE ?", "%"+escaped+"%")
+	}
+
+	var total int64
+
+	if result := pinsQuery.Count(&total); result.Error != nil {
+		return nil, result.Error
+	}
+
+	if total == 0 {
+		content := "No messages have been pinned yet."
+		if query != "" {
+			content = fmt.Sprintf("No pinned messages contain %q.", query)
+		}
+
+		return &discordgo.InteractionResponseData{Content: content, Embeds: []*discordgo.MessageEmbed{}, Components: []discordgo.MessageComponent{}, Flags: discordgo.MessageFlagsEphemeral}, nil
+	}
+
+	pages := int((total + pinsPageSize - 1) / pinsPageSize)
+
+	if page > pages {
+		page = pages
+	}
+
+	pinnedMessages := []models.PinnedMessage{}
+
+	result := pinsQuery.Order("created_at DESC").Offset((page - 1) * pinsPageSize).Limit(pinsPageSize).Find(&pinnedMessages)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	lines := make([]string, len(pinnedMessages))
+
+	for index, pinnedMessage := range pinnedMessages {
+		content := strings.ReplaceAll(pinnedMessage.Content, "\n", " ")
+		if content == "" {
+			content = "*No text*"
+		}
+
+		jump := fmt.Sprintf("[Jump](%s)", utils.MessageURL(pinnedMessage.GuildId, pinnedMessage.ChannelId, pinnedMessage.MessageId))
+		if pinnedMessage.OriginalDeleted {
+			jump = "*Original deleted*"
+		}
+
+		lines[index] = fmt.Sprintf(
+			"**#%v** <@%s> in <#%s>, <t:%v:R> %s\n%s",
+			pinnedMessage.ID, pinnedMessage.AuthorId, pinnedMessage.ChannelId, pinnedMessage.CreatedAt.Unix(),
+			jump, utils.Truncate(content, 200),
+		)
+	}
+
+	title := "Pinned messages"
+	if query != "" {
+		title = fmt.Sprintf("Pinned messages containing %q", query)
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: "",
+		Flags:   discordgo.MessageFlagsEphemeral,
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       utils.Truncate(title, 256),
+				Description: strings.Join(lines, "\n\n"),
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: fmt.Sprintf("Page %v of %v, %v pinned messages", page, pages, total),
+				},
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Previous",
+						Style:    discordgo.SecondaryButton,
+						CustomID: utils.CustomID("pins_page", strconv.Itoa(page-1), query),
+						Disabled: page <= 1,
+					},
+					discordgo.Button{
+						Label:    "Next",
+						Style:    discordgo.SecondaryButton,
+						CustomID: utils.CustomID("pins_page", strconv.Itoa(page+1), query),
+						Disabled: page >= pages,
+					},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, nil
+}
+
+func pinnedMessageEmbed(pinnedMessage models.PinnedMessage) *discordgo.MessageEmbed {
+	// Starboard pins and native pins whose pinner isn't in the audit log have none
+	pinner := "Unknown"
+	switch {
+	case pinnedMessage.PinnerId != "":
+		pinner = fmt.Sprintf("<@%s>", pinnedMessage.PinnerId)
+	case pinnedMessage.Stars > 0:
+		pinner = "Starboard"
+	}
+
+	return &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: pinnedMessage.AuthorName,
+		},
+		Description: utils.Truncate(pinnedMessage.Content, 4096),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Channel", Value: fmt.Sprintf("<#%s>", pinnedMessage.ChannelId), Inline: true},
+			{Name: "Pinned by", Value: pinner, Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Pin #%v", pinnedMessage.ID),
+		},
+		Timestamp: pinnedMessage.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func configStarboardHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	// Map so that false and 0 aren't skipped as zero values
+	configUpdate := map[string]interface{}{
+		"starboard_threshold": 0,
+	}
+
+	switch subCommand.Name {
+	case "enable":
+		emoji := "⭐"
+
+		if option, ok := subCommandOptionMap["emoji"]; ok {
+			parsed, ok := utils.ParseStarboardEmoji(option.StringValue())
+
+			if !ok {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral("Please provide a single emoji, like ⭐ or a custom server emoji."))
+				return
+			}
+
+			emoji = parsed
+		}
+
+		selfStar := false
+		if option, ok := subCommandOptionMap["self_star"]; ok {
+			selfStar = option.BoolValue()
+		}
+
+		configUpdate["starboard_threshold"] = subCommandOptionMap["threshold"].IntValue()
+		configUpdate["starboard_emoji"] = emoji
+		configUpdate["starboard_self_star"] = selfStar
+	}
+
+	result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(configUpdate)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	default:
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Successfully updated config!",
+			},
+		})
+	}
+}
+
+func configPinsHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	if strings.HasPrefix(subCommand.Name, "route_") {
+		configPinRoutesHandler(db, s, i, subCommand.Name, subCommandOptionMap)
+		return
+	}
+
+	if strings.HasPrefix(subCommand.Name, "role_") {
+		configPinRolesHandler(db, s, i, subCommand.Name, subCommandOptionMap)
+		return
+	}
+
+	// Map so that false and empty values aren't skipped as zero values
+	configUpdate := map[string]interface{}{}
+
+	switch subCommand.Name {
+	case "native":
+		unpin := false
+		if option, ok := subCommandOptionMap["unpin"]; ok {
+			unpin = option.BoolValue()
+		}
+
+		configUpdate["pins_mirror_native"] = subCommandOptionMap["mirror"].BoolValue()
+		configUpdate["pins_unpin_native"] = unpin
+	case "sync":
+		configUpdate["pins_sync_originals"] = subCommandOptionMap["enabled"].BoolValue()
+	case "attribution":
+		configUpdate["pins_as_author"] = subCommandOptionMap["as_author"].BoolValue()
+	case "approval":
+		configUpdate["pins_approval_channel_id"] = ""
+
+		if option, ok := subCommandOptionMap["channel"]; ok {
+			configUpdate["pins_approval_channel_id"] = option.Value.(string)
+		}
+	}
+
+	result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(configUpdate)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	default:
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Successfully updated config!",
+			},
+		})
+	}
+}
+
+func configPinRoutesHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommandName string, subCommandOptionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	switch subCommandName {
+	case "route_add":
+		sourceId := subCommandOptionMap["source"].Value.(string)
+		pinsChannelId := subCommandOptionMap["channel"].Value.(string)
+
+		if sourceId == pinsChannelId {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Pins can't be sent to the channel they come from."))
+			return
+		}
+
+		pinRoute := models.PinRoute{GuildId: i.GuildID, SourceId: sourceId}
+
+		result := db.Where(&pinRoute).Assign(models.PinRoute{PinsChannelId: pinsChannelId}).FirstOrCreate(&pinRoute)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Pins from <#%s> will be sent to <#%s>.", sourceId, pinsChannelId),
+				},
+			})
+		}
+
+	case "route_remove":
+		sourceId := subCommandOptionMap["source"].Value.(string)
+
+		result := db.Where(&models.PinRoute{GuildId: i.GuildID, SourceId: sourceId}).Delete(&models.PinRoute{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case result.RowsAffected == 0:
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Pins from <#%s> aren't routed.", sourceId)))
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Pins from <#%s> will be sent to the default pins channel.", sourceId),
+				},
+			})
+		}
+
+	case "route_list":
+		var config = models.Config{GuildId: i.GuildID}
+
+		result := db.Where(&config).FirstOrCreate(&config)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		pinRoutes := []models.PinRoute{}
+
+		result = db.Where(&models.PinRoute{GuildId: i.GuildID}).Order("id").Find(&pinRoutes)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		lines := make([]string, 0, len(pinRoutes)+1)
+
+		for _, pinRoute := range pinRoutes {
+			lines = append(lines, fmt.Sprintf("<#%s> → <#%s>", pinRoute.SourceId, pinRoute.PinsChannelId))
+		}
+
+		fallback := "not pinned, no pins channel is set"
+		if config.PinsChannelId != "" {
+			fallback = fmt.Sprintf("<#%s>", config.PinsChannelId)
+		}
+
+		lines = append(lines, fmt.Sprintf("Everything else → %s", fallback))
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: utils.Truncate(strings.Join(lines, "\n"), 2000),
+			},
+		})
+	}
+}
+
+func configPinRolesHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommandName string, subCommandOptionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	switch subCommandName {
+	case "role_add":
+		roleId := subCommandOptionMap["role"].Value.(string)
+
+		pinRole := models.PinRole{GuildId: i.GuildID, RoleId: roleId}
+
+		result := db.Where(&pinRole).FirstOrCreate(&pinRole)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content:         fmt.Sprintf("Members with <@&%s> can pin messages.", roleId),
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+		}
+
+	case "role_remove":
+		roleId := subCommandOptionMap["role"].Value.(string)
+
+		result := db.Where(&models.PinRole{GuildId: i.GuildID, RoleId: roleId}).Delete(&models.PinRole{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case result.RowsAffected == 0:
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("That role can't pin messages."))
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content:         fmt.Sprintf("Members with <@&%s> can no longer pin messages.", roleId),
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+		}
+
+	case "role_list":
+		var config = models.Config{GuildId: i.GuildID}
+
+		result := db.Where(&config).FirstOrCreate(&config)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		pinRoles := []models.PinRole{}
+
+		result = db.Where(&models.PinRole{GuildId: i.GuildID}).Order("id").Find(&pinRoles)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		// Pinning is only restricted once a pin role or an approval channel is set
+		if len(pinRoles) == 0 && config.PinsApprovalChannelId == "" {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "There are no pin roles or approval channel, so everyone can pin messages. Setting either limits pinning to members with a pin role and members with the Manage Messages permission.",
+				},
+			})
+			return
+		}
+
+		roles := []string{"members with the Manage Messages permission"}
+		for _, pinRole := range pinRoles {
+			roles = append(roles, fmt.Sprintf("<@&%s>", pinRole.RoleId))
+		}
+
+		approval := "Other members can't pin messages."
+		if config.PinsApprovalChannelId != "" {
+			approval = fmt.Sprintf("Other members can request pins, reviewed in <#%s>.", config.PinsApprovalChannelId)
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         utils.Truncate(fmt.Sprintf("Pinning without approval: %s\n%s", strings.Join(roles, ", "), approval), 2000),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/utils"
+	"log"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+var verificationLevelNames = map[discordgo.VerificationLevel]string{
+	discordgo.VerificationLevelNone:     "none",
+	discordgo.VerificationLevelLow:      "low",
+	discordgo.VerificationLevelMedium:   "medium",
+	discordgo.VerificationLevelHigh:     "high",
+	discordgo.VerificationLevelVeryHigh: "highest",
+}
+
+func configRaidsHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	// Map so that 0 and empty values aren't skipped as zero values
+	configUpdate := map[string]interface{}{}
+
+	switch subCommand.Name {
+	case "threshold":
+		configUpdate["raid_join_threshold"] = subCommandOptionMap["joins"].IntValue()
+		configUpdate["raid_join_window_seconds"] = subCommandOptionMap["seconds"].IntValue()
+	case "alert_channel":
+		configUpdate["raid_alert_channel_id"] = ""
+
+		if option, ok := subCommandOptionMap["channel"]; ok {
+			configUpdate["raid_alert_channel_id"] = option.Value.(string)
+		}
+	}
+
+	result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(configUpdate)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	default:
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Successfully updated config!",
+			},
+		})
+	}
+}
+
+// Raises the verification level of the guild to the highest from a raid alert
+func raidLockdownComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		guild, ok := raidGuild(s, i)
+		if !ok {
+			return
+		}
+
+		if guild.VerificationLevel == discordgo.VerificationLevelVeryHigh {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("The verification level is already the highest."))
+			return
+		}
+
+		level := discordgo.VerificationLevelVeryHigh
+
+		_, err := s.GuildEdit(i.GuildID, &discordgo.GuildParams{VerificationLevel: &level})
+
+		if err != nil {
+			log.Printf("Failed to lock down %v: %v", i.GuildID, err)
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Failed to raise the verification level, check that I have the Manage Server permission."))
+			return
+		}
+
+		raidUpdateAlert(s, i, fmt.Sprintf("Locked down by <@%s>, the verification level was raised from %s to %s.", i.Member.User.ID, verificationLevelNames[guild.VerificationLevel], verificationLevelNames[level]), discordgo.Button{
+			Label:    "Lift lockdown",
+			Style:    discordgo.SecondaryButton,
+			CustomID: utils.CustomID("raid_lift", strconv.Itoa(int(guild.VerificationLevel))),
+		})
+	}
+}
+
+// Restores the verification level the guild had before its lockdown
+func raidLiftComponentHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		_, args := utils.ParseCustomID(i.MessageComponentData().CustomID)
+
+		if len(args) != 1 {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		previousLevel, err := strconv.Atoi(args[0])
+		if err != nil {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		if _, ok := raidGuild(s, i); !ok {
+			return
+		}
+
+		level := discordgo.VerificationLevel(previousLevel)
+
+		_, err = s.GuildEdit(i.GuildID, &discordgo.GuildParams{VerificationLevel: &level})
+
+		if err != nil {
+			log.Printf("Failed to lift lockdown of %v: %v", i.GuildID, err)
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Failed to restore the verification level, check that I have the Manage Server permission."))
+			return
+		}
+
+		raidUpdateAlert(s, i, fmt.Sprintf("Lockdown lifted by <@%s>, the verification level was restored to %s.", i.Member.User.ID, verificationLevelNames[level]))
+	}
+}
+
+// Returns the guild of a raid alert interaction if its member may manage it
+func raidGuild(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.Guild, bool) {
+	if i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("You need the Manage Server permission to do that."))
+		return nil, false
+	}
+
+	guild, err := s.State.Guild(i.GuildID)
+
+	if err != nil {
+		guild, err = s.Guild(i.GuildID)
+
+		if err != nil {
+			log.Print(err)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return nil, false
+		}
+	}
+
+	return guild, true
+}
+
+// Adds status to the raid alert and replaces its buttons
+func raidUpdateAlert(s *discordgo.Session, i *discordgo.InteractionCreate, status string, buttons ...discordgo.MessageComponent) {
+	embeds := i.Message.Embeds
+
+	if len(embeds) > 0 {
+		embeds[0].Fields = append(embeds[0].Fields, &discordgo.MessageEmbedField{
+			Name:  "Lockdown",
+			Value: status,
+		})
+	}
+
+	components := []discordgo.MessageComponent{}
+
+	if len(buttons) > 0 {
+		components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: buttons},
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     embeds,
+			Components: components,
+		},
+	})
+}
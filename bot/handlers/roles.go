@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/utils"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+func configAutoRolesHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	forBots := false
+	if option, ok := subCommandOptionMap["target"]; ok {
+		forBots = option.StringValue() == "bots"
+	}
+
+	switch subCommand.Name {
+	case "add":
+		// Resolved roles are always sent with the interaction, unlike the state and REST lookups of RoleValue
+		role, ok := i.ApplicationCommandData().Resolved.Roles[subCommandOptionMap["role"].Value.(string)]
+		if !ok {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		if err := validateAutoRole(s, i.GuildID, role); err != nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(err.Error()))
+			return
+		}
+
+		autoRole := models.AutoRole{GuildId: i.GuildID, RoleId: role.ID}
+
+		// Map so that false isn't skipped as a zero value
+		result := db.Where(&autoRole).Where(map[string]interface{}{"for_bots": forBots}).Attrs(models.AutoRole{ForBots: forBots}).FirstOrCreate(&autoRole)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content:         fmt.Sprintf("New %s will be given <@&%s>.", autoRoleTarget(forBots), role.ID),
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+		}
+
+	case "remove":
+		roleId := subCommandOptionMap["role"].Value.(string)
+
+		result := db.Where(&models.AutoRole{GuildId: i.GuildID, RoleId: roleId}).Where(map[string]interface{}{"for_bots": forBots}).Delete(&models.AutoRole{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case result.RowsAffected == 0:
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("That role isn't given to new %s.", autoRoleTarget(forBots))))
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content:         fmt.Sprintf("New %s will no longer be given <@&%s>.", autoRoleTarget(forBots), roleId),
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+		}
+
+	case "list":
+		var config = models.Config{GuildId: i.GuildID}
+
+		result := db.Where(&config).FirstOrCreate(&config)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		autoRoles := []models.AutoRole{}
+
+		result = db.Where(&models.AutoRole{GuildId: i.GuildID}).Order("id").Find(&autoRoles)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case len(autoRoles) == 0:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "No roles are given to new members.",
+				},
+			})
+
+		default:
+			humans, bots := []string{}, []string{}
+
+			for _, autoRole := range autoRoles {
+				if autoRole.ForBots {
+					bots = append(bots, fmt.Sprintf("<@&%s>", autoRole.RoleId))
+				} else {
+					humans = append(humans, fmt.Sprintf("<@&%s>", autoRole.RoleId))
+				}
+			}
+
+			lines := []string{
+				fmt.Sprintf("Humans: %s", strings.Join(humans, ", ")),
+				fmt.Sprintf("Bots: %s", strings.Join(bots, ", ")),
+				fmt.Sprintf("Delay: %vs after passing membership screening", config.AutoRoleDelaySeconds),
+			}
+
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content:         strings.Join(lines, "\n"),
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+		}
+
+	case "delay":
+		// Map so that 0 isn't skipped as a zero value
+		result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(map[string]interface{}{
+			"auto_role_delay_seconds": subCommandOptionMap["seconds"].IntValue(),
+		})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully updated config!",
+				},
+			})
+		}
+	}
+}
+
+// Checks that the bot is able to give role to members
+func validateAutoRole(s *discordgo.Session, guildId string, role *discordgo.Role) error {
+	switch {
+	case role.ID == guildId:
+		return errors.New("Everyone already has @everyone.")
+	case role.Managed:
+		return fmt.Errorf("<@&%s> is managed by an integration and can't be given manually.", role.ID)
+	}
+
+	position, err := utils.BotTopRolePosition(s, guildId)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("Couldn't check the roles of the bot, try again later.")
+	}
+
+	if position <= role.Position {
+		return fmt.Errorf("<@&%s> is above my highest role, move my role above it first.", role.ID)
+	}
+
+	return nil
+}
+
+func autoRoleTarget(forBots bool) string {
+	if forBots {
+		return "bots"
+	}
+
+	return "members"
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/packages/charts"
+	"kodachi/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Member joins, leaves and growth of a single day
+type memberStatsDay struct {
+	Date   time.Time
+	Joins  int
+	Leaves int
+	Growth int // Net growth since the start of the range
+}
+
+func statsCommandHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := i.ApplicationCommandData().Options
+
+		subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options[0].Options))
+		for _, opt := range options[0].Options {
+			subCommandOptionMap[opt.Name] = opt
+		}
+
+		switch options[0].Name {
+		case "members":
+			days := 30
+
+			if option, ok := subCommandOptionMap["range"]; ok {
+				days, _ = strconv.Atoi(strings.TrimSuffix(option.StringValue(), "d"))
+			}
+
+			// Rendering can take longer than the 3 seconds to respond
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			})
+
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			since := today.AddDate(0, 0, -days+1)
+
+			joins := []models.MemberJoin{}
+			leaves := []models.MemberLeave{}
+
+			result := db.Where(&models.MemberJoin{GuildId: i.GuildID}).Where("created_at >= ?", since).Find(&joins)
+
+			if result.Error == nil {
+				result = db.Where(&models.MemberLeave{GuildId: i.GuildID}).Where("created_at >= ?", since).Find(&leaves)
+			}
+
+			if result.Error != nil {
+				log.Print(result.Error)
+
+				content := responses.GenericErrorResponse.Data.Content
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+				return
+			}
+
+			stats := make([]memberStatsDay, days)
+
+			for index := range stats {
+				stats[index].Date = since.AddDate(0, 0, index)
+			}
+
+			invited, newAccounts := 0, 0
+
+			for _, join := range joins {
+				if day := memberStatsDayIndex(join.CreatedAt, since); day < len(stats) {
+					stats[day].Joins++
+				}
+
+				if join.InviteCode != "" {
+					invited++
+				}
+
+				if !join.AccountCreatedAt.IsZero() && join.CreatedAt.Sub(join.AccountCreatedAt) < utils.NewAccountAge {
+					newAccounts++
+				}
+			}
+
+			for _, leave := range leaves {
+				if day := memberStatsDayIndex(leave.CreatedAt, since); day < len(stats) {
+					stats[day].Leaves++
+				}
+			}
+
+			growth := 0
+
+			for index := range stats {
+				growth += stats[index].Joins - stats[index].Leaves
+				stats[index].Growth = growth
+			}
+
+			chart, err := memberStatsChart(stats, days)
+
+			if err != nil {
+				log.Print(err)
+
+				content := responses.GenericErrorResponse.Data.Content
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+				return
+			}
+
+			content := fmt.Sprintf(
+				"**%v** joins (%v through tracked invites, %v new accounts), **%v** leaves, **%+d** net growth over the last %v days.",
+				len(joins), invited, newAccounts, len(leaves), growth, days,
+			)
+
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: &content,
+				Files: []*discordgo.File{
+					{
+						Name:        "members.png",
+						ContentType: "image/png",
+						Reader:      bytes.NewReader(chart),
+					},
+					{
+						Name:        "members.csv",
+						ContentType: "text/csv",
+						Reader:      bytes.NewReader(memberStatsCSV(stats)),
+					},
+				},
+			})
+
+			if err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// Returns the index of the day t falls on, counted from since
+func memberStatsDayIndex(t, since time.Time) int {
+	return int(t.Sub(since) / (24 * time.Hour))
+}
+
+func memberStatsChart(stats []memberStatsDay, days int) ([]byte, error) {
+	chart := charts.LineChart{
+		Title: fmt.Sprintf("Members over the last %v days", days),
+		Series: []charts.Series{
+			{Name: "Joins", Color: "#57F287"},
+			{Name: "Leaves", Color: "#ED4245"},
+			{Name: "Net growth", Color: "#5865F2"},
+		},
+	}
+
+	for _, day := range stats {
+		chart.Labels = append(chart.Labels, day.Date.Format("Jan 2"))
+		chart.Series[0].Values = append(chart.Series[0].Values, day.Joins)
+		chart.Series[1].Values = append(chart.Series[1].Values, day.Leaves)
+		chart.Series[2].Values = append(chart.Series[2].Values, day.Growth)
+	}
+
+	return charts.DrawLineChart(chart)
+}
+
+func memberStatsCSV(stats []memberStatsDay) []byte {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"date", "joins", "leaves", "net_growth"})
+
+	for _, day := range stats {
+		writer.Write([]string{
+			day.Date.Format("2006-01-02"),
+			strconv.Itoa(day.Joins),
+			strconv.Itoa(day.Leaves),
+			strconv.Itoa(day.Growth),
+		})
+	}
+
+	writer.Flush()
+
+	return buffer.Bytes()
+}
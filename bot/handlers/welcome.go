@@ -0,0 +1,682 @@
+package handlers
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/bot/responses"
+	"kodachi/packages/templates"
+	"kodachi/utils"
+	"log"
+	"net/url"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Embeds editable through "/config <kind> embed", by kind
+var configEmbeds = map[string]struct {
+	title          string
+	columnPrefix   string
+	previewCommand string
+	variables      []string
+	template       func(config models.Config) utils.EmbedTemplate
+}{
+	"welcome": {"Welcome embed", "welcome_embed_", "/welcome test", utils.WelcomeTemplateVariables, utils.WelcomeEmbedTemplate},
+	"goodbye": {"Goodbye embed", "goodbye_embed_", "/welcome test_goodbye", utils.GoodbyeTemplateVariables, utils.GoodbyeEmbedTemplate},
+}
+
+func configWelcomeHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	var config = models.Config{GuildId: i.GuildID}
+
+	result := db.Where(&config).FirstOrCreate(&config)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	switch subCommand.Name {
+	case "embed":
+		configEmbedModal(s, i, "welcome", config)
+
+	case "card":
+		subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+		for _, opt := range subCommand.Options {
+			subCommandOptionMap[opt.Name] = opt
+		}
+
+		// Map so that disabling isn't skipped as a zero value
+		configUpdate := map[string]interface{}{
+			"welcome_card_enabled": subCommandOptionMap["enabled"].BoolValue(),
+		}
+
+		if option, ok := subCommandOptionMap["background_color"]; ok {
+			color, err := utils.ParseHexColor(option.StringValue())
+
+			if err != nil {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Background color is invalid: %v", err)))
+				return
+			}
+
+			configUpdate["welcome_card_background_color"] = color
+		}
+
+		if option, ok := subCommandOptionMap["background_url"]; ok {
+			backgroundURL := option.StringValue()
+
+			if backgroundURL == "none" {
+				backgroundURL = ""
+			} else if _, err := url.ParseRequestURI(backgroundURL); err != nil {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral("Please provide a valid background url."))
+				return
+			}
+
+			configUpdate["welcome_card_background_url"] = backgroundURL
+			// Downloaded again on the next welcome
+			configUpdate["welcome_card_background_key"] = ""
+		}
+
+		result := db.Model(&config).Updates(configUpdate)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully updated welcome card! Use /welcome test to preview it.",
+				},
+			})
+		}
+	}
+}
+
+// Opens the modal editing the embed of the given kind
+func configEmbedModal(s *discordgo.Session, i *discordgo.InteractionCreate, kind string, config models.Config) {
+	configEmbed := configEmbeds[kind]
+	embed := configEmbed.template(config)
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: utils.CustomID("config_embed", kind),
+			Title:    configEmbed.title,
+			Components: []discordgo.MessageComponent{
+				textInputRow(discordgo.TextInput{
+					CustomID:    "title",
+					Label:       "Title",
+					Style:       discordgo.TextInputShort,
+					Placeholder: "{username} joined {server}!",
+					Value:       embed.Title,
+					MaxLength:   256,
+				}),
+				textInputRow(discordgo.TextInput{
+					CustomID:    "description",
+					Label:       "Description",
+					Style:       discordgo.TextInputParagraph,
+					Placeholder: "{mention}, we are now {member_count} members.",
+					Value:       embed.Description,
+					MaxLength:   4000,
+				}),
+				textInputRow(discordgo.TextInput{
+					CustomID:    "color",
+					Label:       "Color",
+					Style:       discordgo.TextInputShort,
+					Placeholder: "#5865F2",
+					Value:       utils.FormatHexColor(embed.Color),
+					MaxLength:   7,
+				}),
+				textInputRow(discordgo.TextInput{
+					CustomID:    "image_url",
+					Label:       "Image URL",
+					Style:       discordgo.TextInputShort,
+					Placeholder: "https://example.com/banner.png",
+					Value:       embed.ImageURL,
+				}),
+				textInputRow(discordgo.TextInput{
+					CustomID:    "footer",
+					Label:       "Footer",
+					Style:       discordgo.TextInputShort,
+					Placeholder: "Member #{member_count}",
+					Value:       embed.Footer,
+					MaxLength:   2048,
+				}),
+			},
+		},
+	})
+
+	if err != nil {
+		log.Printf("Failed to open %s embed modal: %v", kind, err)
+	}
+}
+
+// Stores the embed submitted through "/config <kind> embed"
+func configEmbedModalHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		data := i.ModalSubmitData()
+		values := utils.ModalValues(data)
+
+		_, args := utils.ParseCustomID(data.CustomID)
+
+		if len(args) != 1 {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		kind := args[0]
+		configEmbed, ok := configEmbeds[kind]
+
+		if !ok {
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		embed := utils.EmbedTemplate{
+			Title:       values["title"],
+			Description: values["description"],
+			ImageURL:    values["image_url"],
+			Footer:      values["footer"],
+		}
+
+		if err := embed.Validate(configEmbed.variables); err != nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Invalid %s: %v", kind, err)))
+			return
+		}
+
+		if values["color"] != "" {
+			color, err := utils.ParseHexColor(values["color"])
+
+			if err != nil {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral(fmt.Sprintf("Color is invalid: %v", err)))
+				return
+			}
+
+			embed.Color = color
+		}
+
+		if embed.ImageURL != "" {
+			if _, err := url.ParseRequestURI(embed.ImageURL); err != nil {
+				s.InteractionRespond(i.Interaction, responses.Ephemeral("Please provide a valid image url."))
+				return
+			}
+		}
+
+		// Map so that emptied fields are cleared as well
+		result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(map[string]interface{}{
+			configEmbed.columnPrefix + "title":       embed.Title,
+			configEmbed.columnPrefix + "description": embed.Description,
+			configEmbed.columnPrefix + "color":       embed.Color,
+			configEmbed.columnPrefix + "image_url":   embed.ImageURL,
+			configEmbed.columnPrefix + "footer":      embed.Footer,
+		})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case !embed.IsSet():
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Successfully removed %s embed.", kind),
+				},
+			})
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Successfully updated %s embed! Use %s to preview it.", kind, configEmbed.previewCommand),
+				},
+			})
+		}
+	}
+}
+
+func textInputRow(input discordgo.TextInput) discordgo.ActionsRow {
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{input},
+	}
+}
+
+func configGoodbyeHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	var config = models.Config{GuildId: i.GuildID}
+
+	result := db.Where(&config).FirstOrCreate(&config)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+		return
+	}
+
+	switch subCommand.Name {
+	case "embed":
+		configEmbedModal(s, i, "goodbye", config)
+	}
+}
+
+// Sets the goodbye message of a type
+func configGoodbyeMessageHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	message := optionMap["message"].StringValue()
+	column := "goodbye_message"
+
+	if option, ok := optionMap["type"]; ok {
+		switch option.StringValue() {
+		case utils.GoodbyeKick:
+			column = "goodbye_kick_message"
+		case utils.GoodbyeBan:
+			column = "goodbye_ban_message"
+		}
+	}
+
+	configMessageHandler(db, s, i, column, message, utils.ParseGoodbyeTemplate)
+}
+
+// Sets a templated message column of the config after validating it, "none" clears it
+func configMessageHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, column, message string, parse func(text string) (*templates.Template, error)) {
+	if message == "none" {
+		message = ""
+	}
+
+	if _, err := parse(message); err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Invalid message: %v", err),
+			},
+		})
+		return
+	}
+
+	result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Update(column, message)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	default:
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Successfully updated config!",
+			},
+		})
+	}
+}
+
+// Downloads the uploaded or linked welcome attachment into the blob store, "none" removes it
+func configWelcomeAttachmentHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	var attachmentURL, attachmentName string
+
+	if option, ok := optionMap["attachment"]; ok {
+		attachment := i.ApplicationCommandData().Resolved.Attachments[option.Value.(string)]
+
+		attachmentURL = attachment.URL
+		attachmentName = attachment.Filename
+	} else if option, ok := optionMap["attachment_url"]; ok {
+		attachmentURL = option.StringValue()
+	} else {
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("Please upload an attachment or provide its url."))
+		return
+	}
+
+	if attachmentURL == "none" {
+		result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(map[string]interface{}{
+			"welcome_message_attachment_url":          "",
+			"welcome_message_attachment_key":          "",
+			"welcome_message_attachment_name":         "",
+			"welcome_message_attachment_content_type": "",
+			"welcome_message_attachment_removed":      "",
+		})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully removed the welcome attachment.",
+				},
+			})
+		}
+		return
+	}
+
+	// Downloading can take longer than the 3 seconds to respond
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	attachment, err := utils.StoreWelcomeAttachment(attachmentURL, attachmentName)
+
+	if err != nil {
+		content := fmt.Sprintf("Invalid attachment: %v", err)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		return
+	}
+
+	// Map so that the removal notice is cleared rather than skipped as a zero value
+	result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(map[string]interface{}{
+		"welcome_message_attachment_url":          attachmentURL,
+		"welcome_message_attachment_key":          attachment.Key,
+		"welcome_message_attachment_name":         attachment.Name,
+		"welcome_message_attachment_content_type": attachment.ContentType,
+		"welcome_message_attachment_removed":      "",
+	})
+
+	content := fmt.Sprintf("Successfully updated config! Stored %s.", attachment.Name)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+		content = responses.GenericErrorResponse.Data.Content
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+}
+
+func welcomeTestGoodbyeHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	user := i.Member.User
+
+	if option, ok := subCommandOptionMap["user"]; ok {
+		user = i.ApplicationCommandData().Resolved.Users[option.UserValue(nil).ID]
+	}
+
+	// Previews use the command author as the moderator
+	reason := utils.GoodbyeReason{Kind: utils.GoodbyeLeave}
+
+	if option, ok := subCommandOptionMap["type"]; ok && option.StringValue() != utils.GoodbyeLeave {
+		reason = utils.GoodbyeReason{Kind: option.StringValue(), Moderator: i.Member.User, Reason: "Testing goodbye messages"}
+	}
+
+	var guildConfig = models.Config{}
+
+	result := db.Where(&models.Config{GuildId: i.GuildID}).First(&guildConfig)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	default:
+		messageContent, messageEmbed, err := utils.RenderGoodbye(s, guildConfig, user, reason)
+
+		if err != nil {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Goodbye message is invalid: %v", err),
+				},
+			})
+			return
+		}
+
+		if messageContent == "" && messageEmbed == nil {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Goodbye message is not configured.",
+				},
+			})
+			return
+		}
+
+		var messageEmbeds []*discordgo.MessageEmbed
+
+		if messageEmbed != nil {
+			messageEmbeds = []*discordgo.MessageEmbed{messageEmbed}
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: messageContent,
+				Embeds:  messageEmbeds,
+				AllowedMentions: &discordgo.MessageAllowedMentions{
+					Parse: []discordgo.AllowedMentionType{},
+				},
+			},
+		})
+	}
+}
+
+func configWelcomeMessagesHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate, subCommand *discordgo.ApplicationCommandInteractionDataOption) {
+	subCommandOptionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(subCommand.Options))
+	for _, opt := range subCommand.Options {
+		subCommandOptionMap[opt.Name] = opt
+	}
+
+	switch subCommand.Name {
+	case "add":
+		welcomeMessage := models.WelcomeMessage{
+			GuildId:  i.GuildID,
+			Template: subCommandOptionMap["message"].StringValue(),
+			Weight:   1,
+		}
+
+		if option, ok := subCommandOptionMap["weight"]; ok {
+			welcomeMessage.Weight = int(option.IntValue())
+		}
+
+		if _, err := utils.ParseWelcomeTemplate(welcomeMessage.Template); err != nil {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Invalid welcome message: %v", err),
+				},
+			})
+			return
+		}
+
+		result := db.Create(&welcomeMessage)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Successfully added welcome message #%v.", welcomeMessage.ID),
+				},
+			})
+		}
+
+	case "remove":
+		welcomeMessage := models.WelcomeMessage{GuildId: i.GuildID}
+		welcomeMessage.ID = uint(subCommandOptionMap["id"].IntValue())
+
+		result := db.Where(&welcomeMessage).Delete(&models.WelcomeMessage{})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case result.RowsAffected == 0:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Welcome message does not exist.",
+				},
+			})
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully removed welcome message.",
+				},
+			})
+		}
+
+	case "list":
+		var config = models.Config{GuildId: i.GuildID}
+
+		result := db.Where(&config).FirstOrCreate(&config)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+			return
+		}
+
+		pool := []models.WelcomeMessage{}
+
+		result = db.Where(&models.WelcomeMessage{GuildId: i.GuildID}).Order("id").Find(&pool)
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		case len(pool) == 0:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "There are no messages in the welcome message pool, the welcome_message config is used instead.",
+				},
+			})
+
+		default:
+			totalWeight := 0
+
+			for _, welcomeMessage := range pool {
+				totalWeight += welcomeMessage.Weight
+			}
+
+			lines := make([]string, len(pool))
+
+			for index, welcomeMessage := range pool {
+				lines[index] = fmt.Sprintf("**#%v** (weight %v, %v%%)\n%s", welcomeMessage.ID, welcomeMessage.Weight, welcomeMessage.Weight*100/totalWeight, utils.Truncate(welcomeMessage.Template, 200))
+			}
+
+			chunks := utils.ChunkLines(lines, "\n\n", 4096)
+
+			embed := &discordgo.MessageEmbed{
+				Title:       "Welcome message pool",
+				Description: chunks[0],
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: fmt.Sprintf("Skipping the %v most recently used messages", config.WelcomeMessageNoRepeat),
+				},
+			}
+
+			if len(chunks) > 1 {
+				embed.Footer.Text += " | Some messages are not shown"
+			}
+
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{embed},
+				},
+			})
+		}
+
+	case "no_repeat":
+		// Map so that 0 isn't skipped as a zero value
+		result := db.Model(&models.Config{}).Where(&models.Config{GuildId: i.GuildID}).Updates(map[string]interface{}{
+			"welcome_message_no_repeat": subCommandOptionMap["count"].IntValue(),
+		})
+
+		switch {
+		case result.Error != nil:
+			log.Print(result.Error)
+			s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+		default:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Successfully updated config!",
+				},
+			})
+		}
+	}
+}
+
+// Suggests pool messages for "/config welcome_messages remove"
+func configAutocompleteHandler(db *gorm.DB) CommandHandler {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := i.ApplicationCommandData().Options
+
+		choices := []*discordgo.ApplicationCommandOptionChoice{}
+
+		if options[0].Name == "welcome_messages" {
+			pool := []models.WelcomeMessage{}
+
+			result := db.Where(&models.WelcomeMessage{GuildId: i.GuildID}).Order("id").Limit(25).Find(&pool)
+
+			if result.Error != nil {
+				log.Print(result.Error)
+			}
+
+			for _, welcomeMessage := range pool {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					// Choice names are capped at 100 characters
+					Name:  utils.Truncate(fmt.Sprintf("#%v: %s", welcomeMessage.ID, welcomeMessage.Template), 100),
+					Value: welcomeMessage.ID,
+				})
+			}
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: choices,
+			},
+		})
+
+		if err != nil {
+			log.Printf("Failed to respond to config autocomplete: %v", err)
+		}
+	}
+}
+
+// Sends the DM welcome to the command author
+func welcomeTestDMHandler(db *gorm.DB, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var guildConfig = models.Config{}
+
+	result := db.Where(&models.Config{GuildId: i.GuildID}).First(&guildConfig)
+
+	switch {
+	case result.Error != nil:
+		log.Print(result.Error)
+		s.InteractionRespond(i.Interaction, responses.GenericErrorResponse)
+
+	case guildConfig.WelcomeDMMessage == "":
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("Welcome DM message is not configured."))
+
+	default:
+		err := utils.SendDM(s, i.Member.User.ID, &discordgo.MessageSend{
+			Content: utils.RenderWelcomeTemplate(guildConfig.WelcomeDMMessage, utils.WelcomeTemplateValues(db, s, i.GuildID, i.Member.User)),
+		})
+
+		if err != nil {
+			s.InteractionRespond(i.Interaction, responses.Ephemeral("Could not send you a DM, check your privacy settings for this server."))
+			return
+		}
+
+		s.InteractionRespond(i.Interaction, responses.Ephemeral("Sent the welcome DM to you."))
+	}
+}
@@ -1,14 +1,108 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Config struct {
 	gorm.Model
-	GuildId                     string
-	WelcomeMessage              string
-	WelcomeMessageAttachmentURL string
-	WelcomeChannelId            string
-	PinsChannelId               string
+	GuildId                             string
+	WelcomeMessage                      string
+	WelcomeMessageAttachmentURL         string
+	WelcomeMessageAttachmentKey         string // Key of the downloaded attachment in the blob store
+	WelcomeMessageAttachmentName        string
+	WelcomeMessageAttachmentContentType string
+	WelcomeMessageAttachmentRemoved     string // Why a legacy attachment that couldn't be downloaded was removed, until a new one is set
+	WelcomeChannelId                    string
+	PinsChannelId                       string
+	BirthdayChannelId                   string
+	// Welcome embed, sent along the welcome message if any part is set
+	WelcomeEmbedTitle       string
+	WelcomeEmbedDescription string
+	WelcomeEmbedColor       int
+	WelcomeEmbedImageURL    string
+	WelcomeEmbedFooter      string
+	// Generated welcome card, replaces the welcome message attachment if enabled
+	WelcomeCardEnabled         bool
+	WelcomeCardBackgroundColor int
+	WelcomeCardBackgroundURL   string
+	WelcomeCardBackgroundKey   string // Key of the downloaded background in the blob store, cleared when the URL changes
+	WelcomeMessageNoRepeat     int    // How many recently used pool messages are skipped
+	WelcomeDMMessage           string
+	WelcomeDelivery            string // "channel" (default), "dm" or "both"
+	WelcomeAfterScreening      bool   // Wait for membership screening before welcoming
+	AutoRoleDelaySeconds       int
+	RaidJoinThreshold          int // Joins within RaidJoinWindowSeconds that start a raid, 0 disables raid detection
+	RaidJoinWindowSeconds      int
+	RaidAlertChannelId         string
+	StarboardEmoji             string // Unicode emoji or "name:id" for custom emojis, defaults to ⭐
+	StarboardThreshold         int    // Reactions that pin a message, 0 disables the starboard
+	StarboardSelfStar          bool   // Count the author's own reaction
+	PinsMirrorNative           bool   // Copy messages pinned with Discord's own pins to the pins channel
+	PinsUnpinNative            bool   // Unpin mirrored messages so that channels don't reach the pin limit
+	PinsSyncOriginals          bool   // Edit pinned copies when their originals are edited or deleted
+	PinsAsAuthor               bool   // Send pinned copies as their author instead of the pinner
+	PinsApprovalChannelId      string // Members without a pin role request pins here, empty disables requests
+	GoodbyeChannelId           string
+	GoodbyeMessage             string
+	GoodbyeKickMessage         string // Falls back to GoodbyeMessage if empty
+	GoodbyeBanMessage          string // Falls back to GoodbyeMessage if empty
+	// Goodbye embed, sent along the goodbye message if any part is set
+	GoodbyeEmbedTitle       string
+	GoodbyeEmbedDescription string
+	GoodbyeEmbedColor       int
+	GoodbyeEmbedImageURL    string
+	GoodbyeEmbedFooter      string
+}
+
+// Pool of welcome messages picked from at random, used instead of Config.WelcomeMessage if not empty
+type WelcomeMessage struct {
+	gorm.Model
+	GuildId    string
+	Template   string
+	Weight     int
+	LastUsedAt *time.Time
+}
+
+// Welcome DM that could not be delivered, usually because the member has DMs closed
+type WelcomeDMFailure struct {
+	gorm.Model
+	GuildId string
+	UserId  string
+	Reason  string
+}
+
+// Role given to members when they join, once they pass membership screening
+type AutoRole struct {
+	gorm.Model
+	GuildId string
+	RoleId  string
+	ForBots bool // Given to bots instead of humans
+}
+
+// How a member joined a guild, the invite fields are empty if it couldn't be told
+type MemberJoin struct {
+	gorm.Model
+	GuildId          string
+	UserId           string
+	InviteCode       string
+	InviterId        string
+	AccountCreatedAt time.Time
+}
+
+type MemberLeave struct {
+	gorm.Model
+	GuildId string
+	UserId  string
+}
+
+// Member that joined a guild and hasn't passed membership screening yet
+type PendingMember struct {
+	gorm.Model
+	GuildId string
+	UserId  string
 }
 
 type Birthday struct {
@@ -17,7 +111,83 @@ type Birthday struct {
 	Name       string
 	BirthDay   int64
 	BirthMonth int64
+	BirthYear  int64  // 0 if unknown
 	AuthorId   string // User that added birthday entry
+	GuildId    string // Guild the entry was added from, "" if added in DMs
+}
+
+// Reminder text for an author (GuildId is "") or for a guild (AuthorId is "")
+type BirthdayTemplate struct {
+	gorm.Model
+	AuthorId string
+	GuildId  string
+	Template string
+}
+
+type BirthdayCard struct {
+	gorm.Model
+	GuildId    string
+	UserId     string
+	Year       int
+	BirthDay   int64
+	BirthMonth int64
+	ChannelId  string // Channel the "sign the card" message was sent in
+	MessageId  string
+	Delivered  bool
+}
+
+type BirthdayWish struct {
+	gorm.Model
+	CardId     uint
+	AuthorId   string
+	AuthorName string // Display name at the time of signing
+	Message    string
+}
+
+// Message copied to the pins channel
+type PinnedMessage struct {
+	gorm.Model
+	GuildId          string
+	ChannelId        string
+	MessageId        string
+	AuthorId         string
+	AuthorName       string
+	PinnerId         string
+	PinnerName       string
+	AsAuthor         bool // Sent as the author with the pinner in the footer, rather than the other way around
+	PinsChannelId    string
+	WebhookId        string
+	WebhookMessageId string
+	Content          string // Snapshot of the message when it was pinned, with attachment names
+	Stars            int    // Starboard reactions, shown on the copy
+	OriginalDeleted  bool   // The original was deleted while its copy was kept
+	NativelyPinned   bool   // Mirrored from a native pin that was kept, unpinned along with the copy
+	// Comma separated IDs of attachments linked instead of uploaded, too large or failed to download
+	LinkedAttachmentIds string
+}
+
+// Sends pins from a channel, or every channel of a category, to a pins channel other than the guild's
+type PinRoute struct {
+	gorm.Model
+	GuildId       string
+	SourceId      string // Channel or category
+	PinsChannelId string
+}
+
+// Lets members with the role pin messages without approval
+type PinRole struct {
+	gorm.Model
+	GuildId string
+	RoleId  string
+}
+
+// Pin awaiting approval by a moderator, deleted once reviewed
+type PinRequest struct {
+	gorm.Model
+	GuildId     string
+	ChannelId   string
+	MessageId   string
+	RequesterId string
 }
 
 type TreeMember struct {
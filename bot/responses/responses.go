@@ -15,3 +15,21 @@ var NoPinsChannelConfigured = &discordgo.InteractionResponse{
 		Content: "This guild does not have a pins channel configured.",
 	},
 }
+
+var InvalidUserId = &discordgo.InteractionResponse{
+	Type: discordgo.InteractionResponseChannelMessageWithSource,
+	Data: &discordgo.InteractionResponseData{
+		Content: "Please provide a valid user ID or mention.",
+	},
+}
+
+// Response only visible to the user that triggered the interaction
+func Ephemeral(content string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}
+}
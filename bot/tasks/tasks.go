@@ -3,7 +3,10 @@ package tasks
 import (
 	"fmt"
 	"kodachi/bot/models"
+	"kodachi/packages/templates"
+	"kodachi/utils"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -31,7 +34,7 @@ func BirthdayCheck(db *gorm.DB, s *discordgo.Session) func() {
 				if err != nil {
 					log.Printf("Could not initiate DMs with %v: %v", birthday.AuthorId, err)
 				} else {
-					s.ChannelMessageSend(ch.ID, fmt.Sprintf("Friendly Reminder: Today, %s (<@%s>, %s) was born!\n\nIt's their birthday 🎉🥳", birthday.Name, birthday.UserId, birthday.UserId))
+					s.ChannelMessageSend(ch.ID, birthdayReminder(db, birthday, current))
 				}
 
 				time.Sleep(5 * time.Second)
@@ -39,3 +42,198 @@ func BirthdayCheck(db *gorm.DB, s *discordgo.Session) func() {
 		}
 	}
 }
+
+// Renders the reminder for a birthday entry, using the default template if the stored one can't be used
+func birthdayReminder(db *gorm.DB, birthday models.Birthday, current time.Time) string {
+	text, err := utils.BirthdayTemplate(db, birthday.AuthorId, birthday.GuildId)
+
+	if err != nil {
+		log.Printf("An error ocurred while querying for birthday template: %v", err)
+		text = utils.DefaultBirthdayTemplate
+	}
+
+	template, err := templates.Parse(text, utils.BirthdayTemplateVariables)
+
+	if err != nil {
+		log.Printf("Invalid birthday template for %v: %v", birthday.AuthorId, err)
+		template, _ = templates.Parse(utils.DefaultBirthdayTemplate, utils.BirthdayTemplateVariables)
+	}
+
+	return template.Render(utils.BirthdayTemplateValues(birthday, current))
+}
+
+// How many days before a birthday its card is opened for wishes
+const birthdayCardLeadDays = 3
+
+// Opens birthday cards in configured guilds ahead of time and delivers the ones due today
+func BirthdayCardCheck(db *gorm.DB, s *discordgo.Session) func() {
+	return func() {
+		current := time.Now().UTC()
+
+		deliverBirthdayCards(db, s, current)
+		openBirthdayCards(db, s, current)
+	}
+}
+
+func openBirthdayCards(db *gorm.DB, s *discordgo.Session, current time.Time) {
+	guildConfigs := []models.Config{}
+
+	result := db.Where("birthday_channel_id <> ?", "").Find(&guildConfigs)
+
+	if result.Error != nil {
+		log.Printf("An error ocurred while querying for birthday channels: %v", result.Error)
+		return
+	}
+
+	for days := 1; days <= birthdayCardLeadDays; days++ {
+		date := current.AddDate(0, 0, days)
+
+		userBirthdays := []models.Birthday{}
+
+		result := db.Where(&models.Birthday{BirthMonth: int64(date.Month()), BirthDay: int64(date.Day())}).Find(&userBirthdays)
+
+		if result.Error != nil {
+			log.Printf("An error ocurred while querying for birthdays: %v", result.Error)
+			continue
+		}
+
+		for _, guildConfig := range guildConfigs {
+			// Several authors can add the same user, one card is enough
+			userIds := make(map[string]bool)
+
+			// Reminders added in other guilds or in DMs are private to their authors, unless users added themselves
+			for _, birthday := range userBirthdays {
+				if birthday.GuildId == guildConfig.GuildId || birthday.AuthorId == birthday.UserId {
+					userIds[birthday.UserId] = true
+				}
+			}
+
+			for userId := range userIds {
+				if _, err := s.GuildMember(guildConfig.GuildId, userId); err != nil {
+					continue
+				}
+
+				card := models.BirthdayCard{GuildId: guildConfig.GuildId, UserId: userId, Year: date.Year()}
+
+				result := db.Where(&card).FirstOrInit(&card)
+
+				if result.Error != nil {
+					log.Print(result.Error)
+					continue
+				}
+
+				// Card already opened
+				if card.ID != 0 {
+					continue
+				}
+
+				card.BirthDay = int64(date.Day())
+				card.BirthMonth = int64(date.Month())
+				card.ChannelId = guildConfig.BirthdayChannelId
+
+				if result := db.Create(&card); result.Error != nil {
+					log.Print(result.Error)
+					continue
+				}
+
+				message, err := s.ChannelMessageSendComplex(card.ChannelId, &discordgo.MessageSend{
+					Content: fmt.Sprintf("🎂 <@%s>'s birthday is on the %s of %s! Press the button below to sign their card.", userId, utils.Ordinal(date.Day()), date.Month()),
+					Components: []discordgo.MessageComponent{
+						discordgo.ActionsRow{
+							Components: []discordgo.MessageComponent{
+								discordgo.Button{
+									Label:    "Sign the card",
+									Style:    discordgo.PrimaryButton,
+									CustomID: utils.CustomID("birthday_card_sign", strconv.FormatUint(uint64(card.ID), 10)),
+								},
+							},
+						},
+					},
+					AllowedMentions: &discordgo.MessageAllowedMentions{
+						Parse: []discordgo.AllowedMentionType{},
+					},
+				})
+
+				if err != nil {
+					log.Printf("Failed to open birthday card in %v: %v", card.GuildId, err)
+
+					// Try again on the next check
+					db.Unscoped().Delete(&card)
+					continue
+				}
+
+				db.Model(&card).Update("message_id", message.ID)
+			}
+		}
+	}
+}
+
+func deliverBirthdayCards(db *gorm.DB, s *discordgo.Session, current time.Time) {
+	birthdayCards := []models.BirthdayCard{}
+
+	result := db.Where(&models.BirthdayCard{Year: current.Year(), BirthMonth: int64(current.Month()), BirthDay: int64(current.Day())}).Where("delivered = ?", false).Find(&birthdayCards)
+
+	if result.Error != nil {
+		log.Printf("An error ocurred while querying for birthday cards: %v", result.Error)
+		return
+	}
+
+	for _, card := range birthdayCards {
+		wishes := []models.BirthdayWish{}
+
+		result := db.Where(&models.BirthdayWish{CardId: card.ID}).Order("created_at").Find(&wishes)
+
+		if result.Error != nil {
+			log.Print(result.Error)
+			continue
+		}
+
+		if len(wishes) > 0 {
+			guildName := card.GuildId
+
+			if guild, err := s.Guild(card.GuildId); err == nil {
+				guildName = guild.Name
+			}
+
+			lines := make([]string, len(wishes))
+
+			for i, wish := range wishes {
+				lines[i] = fmt.Sprintf("**%s**\n%s", wish.AuthorName, wish.Message)
+			}
+
+			ch, err := s.UserChannelCreate(card.UserId)
+
+			if err != nil {
+				log.Printf("Could not initiate DMs with %v: %v", card.UserId, err)
+			} else {
+				for _, description := range utils.ChunkLines(lines, "\n\n", 4096) {
+					_, err = s.ChannelMessageSendEmbed(ch.ID, &discordgo.MessageEmbed{
+						Title:       fmt.Sprintf("🎉 Happy birthday from %s!", guildName),
+						Description: description,
+					})
+
+					if err != nil {
+						log.Printf("Failed to deliver birthday card to %v: %v", card.UserId, err)
+						break
+					}
+				}
+			}
+		}
+
+		db.Model(&card).Update("delivered", true)
+
+		// Close the card
+		components := []discordgo.MessageComponent{}
+		content := fmt.Sprintf("🎂 Happy birthday <@%s>! Their card was delivered with %v wishes.", card.UserId, len(wishes))
+
+		s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         card.MessageId,
+			Channel:    card.ChannelId,
+			Content:    &content,
+			Components: components,
+			Embeds:     []*discordgo.MessageEmbed{},
+		})
+
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"kodachi/bot/handlers"
 	"kodachi/bot/models"
 	kodachiTasks "kodachi/bot/tasks"
+	"kodachi/packages/blobs"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
 	"time"
@@ -30,6 +32,9 @@ var db *gorm.DB
 // Parse CLI Arguments
 func init() { flag.Parse() }
 
+// Seed random picks, such as welcome messages
+func init() { rand.Seed(time.Now().UnixNano()) }
+
 // Initiate discord session
 func init() {
 	// Load .env only if --testing=true
@@ -49,10 +54,19 @@ func init() {
 		log.Fatalf("Invalid bot parameters: %v", err)
 	}
 	s.Identify.Intents |= discordgo.IntentGuildMembers
+	s.Identify.Intents |= discordgo.IntentGuildInvites
+	s.Identify.Intents |= discordgo.IntentGuildMessageReactions
 	s.Identify.Intents |= discordgo.IntentGuildWebhooks
 	s.Identify.Intents |= discordgo.IntentMessageContent
 }
 
+// Set where downloaded files, such as welcome attachments, are stored
+func init() {
+	if dir := os.Getenv("BLOB_DIR"); dir != "" {
+		blobs.Dir = dir
+	}
+}
+
 // Initiate database connection
 func init() {
 	var err error
@@ -62,20 +76,98 @@ func init() {
 		log.Fatalf("Could not connect to database: %v", err)
 	}
 
-	if !db.Migrator().HasTable(&models.Config{}) {
-		db.Migrator().CreateTable(&models.Config{})
+	tables := []interface{}{
+		&models.Config{},
+		&models.Birthday{},
+		&models.BirthdayCard{},
+		&models.BirthdayWish{},
+		&models.BirthdayTemplate{},
+		&models.TreeMember{},
+		&models.WelcomeMessage{},
+		&models.WelcomeDMFailure{},
+		&models.AutoRole{},
+		&models.PendingMember{},
+		&models.MemberJoin{},
+		&models.MemberLeave{},
+		&models.PinnedMessage{},
+		&models.PinRoute{},
+		&models.PinRole{},
+		&models.PinRequest{},
 	}
 
-	if !db.Migrator().HasColumn(&models.Config{}, "welcome_channel_id") {
-		db.Migrator().AddColumn(&models.Config{}, "welcome_channel_id")
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			db.Migrator().CreateTable(table)
+		}
 	}
 
-	if !db.Migrator().HasTable(&models.Birthday{}) {
-		db.Migrator().CreateTable(&models.Birthday{})
+	// Columns added to existing tables after they were first created
+	columns := map[interface{}][]string{
+		&models.Config{}: {
+			"welcome_message_attachment_key",
+			"welcome_message_attachment_name",
+			"welcome_message_attachment_content_type",
+			"welcome_message_attachment_removed",
+			"welcome_channel_id",
+			"birthday_channel_id",
+			"welcome_embed_title",
+			"welcome_embed_description",
+			"welcome_embed_color",
+			"welcome_embed_image_url",
+			"welcome_embed_footer",
+			"welcome_card_enabled",
+			"welcome_card_background_color",
+			"welcome_card_background_url",
+			"welcome_card_background_key",
+			"welcome_message_no_repeat",
+			"welcome_dm_message",
+			"welcome_delivery",
+			"goodbye_channel_id",
+			"goodbye_message",
+			"goodbye_kick_message",
+			"goodbye_ban_message",
+			"goodbye_embed_title",
+			"goodbye_embed_description",
+			"goodbye_embed_color",
+			"goodbye_embed_image_url",
+			"goodbye_embed_footer",
+			"auto_role_delay_seconds",
+			"welcome_after_screening",
+			"raid_join_threshold",
+			"raid_join_window_seconds",
+			"raid_alert_channel_id",
+			"starboard_emoji",
+			"starboard_threshold",
+			"starboard_self_star",
+			"pins_mirror_native",
+			"pins_unpin_native",
+			"pins_sync_originals",
+			"pins_as_author",
+			"pins_approval_channel_id",
+		},
+		&models.PinnedMessage{}: {
+			"stars",
+			"original_deleted",
+			"pinner_name",
+			"as_author",
+			"linked_attachment_ids",
+			"natively_pinned",
+		},
+		&models.Birthday{}: {
+			"birth_year",
+			"guild_id",
+		},
+		&models.MemberJoin{}: {
+			"account_created_at",
+		},
 	}
 
-	if !db.Migrator().HasTable(&models.TreeMember{}) {
-		db.Migrator().CreateTable(&models.TreeMember{})
+	for table, tableColumns := range columns {
+		for _, column := range tableColumns {
+			if !db.Migrator().HasColumn(table, column) {
+				db.Migrator().AddColumn(table, column)
+			}
+		}
 	}
 }
 
@@ -87,7 +179,22 @@ func init() {
 		log.Printf("Logged in as: %v#%v", s.State.User.Username, s.State.User.Discriminator)
 	})
 
+	s.AddHandler(kodachiEvents.InviteCacheEventHandler)
+	s.AddHandler(kodachiEvents.InviteCreateEventHandler)
+	s.AddHandler(kodachiEvents.InviteDeleteEventHandler)
 	s.AddHandler(kodachiEvents.WelcomeMessageEventHandler(db))
+	s.AddHandler(kodachiEvents.GoodbyeMessageEventHandler(db))
+	s.AddHandler(kodachiEvents.MemberLeaveEventHandler(db))
+	s.AddHandler(kodachiEvents.StarboardReactionAddEventHandler(db))
+	s.AddHandler(kodachiEvents.StarboardReactionRemoveEventHandler(db))
+	s.AddHandler(kodachiEvents.PinsMirrorEventHandler(db))
+	s.AddHandler(kodachiEvents.PinsMessageUpdateEventHandler(db))
+	s.AddHandler(kodachiEvents.PinsMessageDeleteEventHandler(db))
+	s.AddHandler(kodachiEvents.PinsMessageDeleteBulkEventHandler(db))
+	s.AddHandler(kodachiEvents.AutoRolesMemberAddEventHandler(db))
+	s.AddHandler(kodachiEvents.PendingMemberAddEventHandler(db))
+	s.AddHandler(kodachiEvents.PendingMemberUpdateEventHandler(db))
+	s.AddHandler(kodachiEvents.PendingMemberRemoveEventHandler(db))
 }
 
 // Create websocket connection to Discord
@@ -104,6 +211,7 @@ func init() {
 	scheduler := gocron.NewScheduler(time.UTC)
 
 	scheduler.Every(1).Day().At("00:00").Do(kodachiTasks.BirthdayCheck(db, s))
+	scheduler.Every(1).Day().At("00:00").Do(kodachiTasks.BirthdayCardCheck(db, s))
 
 	scheduler.StartAsync()
 }
@@ -0,0 +1,73 @@
+package blobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Directory blobs are stored in, relative to the working directory unless absolute
+var Dir = "blobs"
+
+// Stores data and returns the key to read it back with.
+// Keys are derived from the content, so storing the same data twice only keeps one copy and blobs
+// may be shared, which is why they aren't deleted.
+func Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+
+	if err := os.MkdirAll(Dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating blob directory: %w", err)
+	}
+
+	path := filepath.Join(Dir, key)
+
+	if _, err := os.Stat(path); err == nil {
+		return key, nil
+	}
+
+	// Written next to the blob then renamed, so that readers never see a partial blob
+	temp, err := os.CreateTemp(Dir, key+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("error creating blob: %w", err)
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return "", fmt.Errorf("error writing blob: %w", err)
+	}
+
+	if err := temp.Close(); err != nil {
+		return "", fmt.Errorf("error writing blob: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return "", fmt.Errorf("error writing blob: %w", err)
+	}
+
+	return key, nil
+}
+
+// Reads the blob stored under key
+func Read(key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return os.ReadFile(filepath.Join(Dir, key))
+}
+
+// Keys are hex encoded SHA-256 sums, which also keeps them from escaping Dir
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(key)
+
+	return err == nil
+}
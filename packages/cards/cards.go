@@ -0,0 +1,122 @@
+package cards
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	cardW = 1024
+	cardH = 400
+
+	avatarRadius = 100.0
+)
+
+// Shared with the server tree renderer
+const fontPath = "/packages/trees/fonts/Roboto/Roboto-Regular.ttf"
+
+type WelcomeCard struct {
+	Avatar          image.Image // nil draws an empty circle
+	Background      image.Image // nil fills the card with BackgroundColor
+	BackgroundColor string      // Hex color, defaults to Discord's dark theme
+	Username        string
+	MemberNumber    int
+}
+
+// Renders the card as a PNG image
+func DrawWelcomeCard(card WelcomeCard) ([]byte, error) {
+	dc := gg.NewContext(cardW, cardH)
+
+	if card.BackgroundColor != "" {
+		dc.SetHexColor(card.BackgroundColor)
+	} else {
+		dc.SetHexColor("#36393f")
+	}
+	dc.Clear()
+
+	if card.Background != nil {
+		drawCover(dc, card.Background, 0, 0, cardW, cardH)
+
+		// Darken the background so the text stays readable
+		dc.SetRGBA(0, 0, 0, 0.35)
+		dc.DrawRectangle(0, 0, cardW, cardH)
+		dc.Fill()
+	}
+
+	avatarX, avatarY := float64(cardW)/2, 40+avatarRadius
+
+	// Avatar ring
+	dc.DrawCircle(avatarX, avatarY, avatarRadius+6)
+	dc.SetRGB(1, 1, 1)
+	dc.Fill()
+
+	if card.Avatar != nil {
+		dc.Push()
+		dc.DrawCircle(avatarX, avatarY, avatarRadius)
+		dc.Clip()
+		drawCover(dc, card.Avatar, avatarX-avatarRadius, avatarY-avatarRadius, 2*avatarRadius, 2*avatarRadius)
+		dc.Pop()
+		dc.ResetClip()
+	} else {
+		dc.DrawCircle(avatarX, avatarY, avatarRadius)
+		dc.SetHexColor("#5865f2")
+		dc.Fill()
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting working directory: %w", err)
+	}
+
+	if err := dc.LoadFontFace(dir+fontPath, 44); err != nil {
+		return nil, fmt.Errorf("error loading font face: %w", err)
+	}
+
+	drawShadowedString(dc, fmt.Sprintf("Welcome, %s!", card.Username), float64(cardW)/2, avatarY+avatarRadius+60)
+
+	if err := dc.LoadFontFace(dir+fontPath, 28); err != nil {
+		return nil, fmt.Errorf("error loading font face: %w", err)
+	}
+
+	drawShadowedString(dc, fmt.Sprintf("Member #%v", card.MemberNumber), float64(cardW)/2, avatarY+avatarRadius+110)
+
+	var buffer bytes.Buffer
+
+	if err := dc.EncodePNG(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Draws im scaled to cover the (x, y, w, h) rectangle, cropping what overflows
+func drawCover(dc *gg.Context, im image.Image, x, y, w, h float64) {
+	bounds := im.Bounds()
+	imW, imH := float64(bounds.Dx()), float64(bounds.Dy())
+
+	scale := w / imW
+	if h/imH > scale {
+		scale = h / imH
+	}
+
+	dc.Push()
+	dc.DrawRectangle(x, y, w, h)
+	dc.Clip()
+	dc.Translate(x+(w-imW*scale)/2, y+(h-imH*scale)/2)
+	dc.Scale(scale, scale)
+	dc.DrawImage(im, 0, 0)
+	dc.Pop()
+	dc.ResetClip()
+}
+
+func drawShadowedString(dc *gg.Context, text string, x, y float64) {
+	dc.SetRGBA(0, 0, 0, 0.6)
+	dc.DrawStringAnchored(text, x+2, y+2, 0.5, 0.5)
+
+	dc.SetRGB(1, 1, 1)
+	dc.DrawStringAnchored(text, x, y, 0.5, 0.5)
+}
@@ -0,0 +1,169 @@
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	chartW = 1200
+	chartH = 600
+
+	paddingTop    = 70.0
+	paddingBottom = 70.0
+	paddingLeft   = 80.0
+	paddingRight  = 40.0
+)
+
+// Shared with the server tree renderer
+const fontPath = "/packages/trees/fonts/Roboto/Roboto-Regular.ttf"
+
+type Series struct {
+	Name   string
+	Color  string // Hex color
+	Values []int  // One value per label
+}
+
+type LineChart struct {
+	Title  string
+	Labels []string // X axis labels, only some are drawn if there are many
+	Series []Series
+}
+
+// Renders the chart as a PNG image
+func DrawLineChart(chart LineChart) ([]byte, error) {
+	dc := gg.NewContext(chartW, chartH)
+
+	dc.SetHexColor("#36393f")
+	dc.Clear()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting working directory: %w", err)
+	}
+
+	if err := dc.LoadFontFace(dir+fontPath, 28); err != nil {
+		return nil, fmt.Errorf("error loading font face: %w", err)
+	}
+
+	dc.SetRGB(1, 1, 1)
+	dc.DrawStringAnchored(chart.Title, chartW/2, paddingTop/2, 0.5, 0.5)
+
+	if err := dc.LoadFontFace(dir+fontPath, 16); err != nil {
+		return nil, fmt.Errorf("error loading font face: %w", err)
+	}
+
+	minValue, maxValue := 0, 1
+
+	for _, series := range chart.Series {
+		for _, value := range series.Values {
+			if value < minValue {
+				minValue = value
+			}
+			if value > maxValue {
+				maxValue = value
+			}
+		}
+	}
+
+	plotW := chartW - paddingLeft - paddingRight
+	plotH := chartH - paddingTop - paddingBottom
+
+	x := func(index int) float64 {
+		if len(chart.Labels) < 2 {
+			return paddingLeft + plotW/2
+		}
+
+		return paddingLeft + plotW*float64(index)/float64(len(chart.Labels)-1)
+	}
+
+	y := func(value int) float64 {
+		return paddingTop + plotH*float64(maxValue-value)/float64(maxValue-minValue)
+	}
+
+	// Horizontal grid lines with their values
+	step := gridStep(maxValue - minValue)
+
+	for value := int(math.Ceil(float64(minValue)/float64(step))) * step; value <= maxValue; value += step {
+		dc.SetRGBA(1, 1, 1, 0.15)
+		dc.SetLineWidth(1)
+		dc.DrawLine(paddingLeft, y(value), chartW-paddingRight, y(value))
+		dc.Stroke()
+
+		dc.SetRGBA(1, 1, 1, 0.7)
+		dc.DrawStringAnchored(fmt.Sprint(value), paddingLeft-10, y(value), 1, 0.5)
+	}
+
+	// At most ~10 labels so that they don't overlap
+	labelEvery := int(math.Ceil(float64(len(chart.Labels)) / 10))
+
+	for index, label := range chart.Labels {
+		if index%labelEvery != 0 && index != len(chart.Labels)-1 {
+			continue
+		}
+
+		dc.SetRGBA(1, 1, 1, 0.7)
+		dc.DrawStringAnchored(label, x(index), chartH-paddingBottom+20, 0.5, 0.5)
+	}
+
+	for _, series := range chart.Series {
+		dc.SetHexColor(series.Color)
+		dc.SetLineWidth(3)
+
+		for index, value := range series.Values {
+			dc.LineTo(x(index), y(value))
+		}
+
+		dc.Stroke()
+	}
+
+	drawLegend(dc, chart.Series)
+
+	var buffer bytes.Buffer
+
+	if err := dc.EncodePNG(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Returns a 1, 2 or 5 times a power of ten distance between grid lines that fits at most 5 of them in span
+func gridStep(span int) int {
+	step, magnitude := 1, 1
+
+	for span/step > 5 {
+		switch step / magnitude {
+		case 1:
+			step = 2 * magnitude
+		case 2:
+			step = 5 * magnitude
+		default:
+			magnitude *= 10
+			step = magnitude
+		}
+	}
+
+	return step
+}
+
+func drawLegend(dc *gg.Context, series []Series) {
+	x := paddingLeft
+	y := chartH - paddingBottom/2 + 10
+
+	for _, s := range series {
+		dc.SetHexColor(s.Color)
+		dc.DrawRectangle(x, y-6, 24, 12)
+		dc.Fill()
+
+		dc.SetRGB(1, 1, 1)
+		dc.DrawStringAnchored(s.Name, x+32, y, 0, 0.5)
+
+		width, _ := dc.MeasureString(s.Name)
+		x += 32 + width + 30
+	}
+}
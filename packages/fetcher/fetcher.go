@@ -0,0 +1,278 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL       = errors.New("only http and https urls can be fetched")
+	ErrBlockedAddress   = errors.New("address is not publicly routable")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrTooLarge         = errors.New("response body is too large")
+	ErrContentType      = errors.New("content type is not allowed")
+)
+
+// Returned for responses other than 200 OK
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %s", e.Status)
+}
+
+// Defaults used for unset Fetcher fields
+const (
+	DefaultTimeout      = 15 * time.Second
+	DefaultMaxSize      = 8 << 20
+	DefaultMaxRedirects = 3
+)
+
+// Fetches urls supplied by users, refusing to reach internal addresses.
+// The zero value is ready to use with the defaults.
+type Fetcher struct {
+	Timeout      time.Duration // For the whole request, including reading the body
+	MaxSize      int64
+	MaxRedirects int
+
+	// Allowed media types, e.g. "image/png", or prefixes ending with "/" like "image/". Empty allows any.
+	ContentTypes []string
+
+	// Allows loopback and private addresses, for httptest servers
+	AllowPrivate bool
+}
+
+type Response struct {
+	Body          io.ReadCloser // Fails with ErrTooLarge past MaxSize, the caller closes it
+	ContentType   string        // Media type without parameters, sniffed if the server didn't send one
+	ContentLength int64         // -1 if unknown
+	URL           *url.URL      // After redirects
+}
+
+// Blocked in addition to the ranges recognized by net.IP
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "This network"
+	mustParseCIDR("100.64.0.0/10"), // Carrier-grade NAT
+	mustParseCIDR("192.0.0.0/24"),  // IETF protocol assignments
+	mustParseCIDR("198.18.0.0/15"), // Benchmarking
+	mustParseCIDR("240.0.0.0/4"),   // Reserved, including broadcast
+	mustParseCIDR("64:ff9b::/96"),  // NAT64, which can reach private IPv4 addresses
+	mustParseCIDR("fc00::/7"),      // Unique local addresses
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *Fetcher) client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		// Checked on the resolved address right before connecting, so that DNS can't point a checked host elsewhere
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if f.AllowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+
+			return nil
+		},
+	}
+
+	maxRedirects := f.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			// Proxies from the environment would be dialed instead of the checked address
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			// Each request gets its own transport, keeping connections around would only leak them
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return ErrTooManyRedirects
+			}
+
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidURL
+			}
+
+			return nil
+		},
+	}
+}
+
+// Requests rawURL and checks the response, the body is streamed
+func (f *Fetcher) Get(rawURL string) (*Response, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	maxSize := f.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedURL.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	body := &limitedBody{
+		reader: bufio.NewReader(resp.Body),
+		closer: resp.Body,
+		cancel: cancel,
+		left:   maxSize,
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	if resp.ContentLength > maxSize {
+		body.Close()
+		return nil, ErrTooLarge
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	if contentType == "" || contentType == "application/octet-stream" {
+		// Peek errors are returned again when reading
+		peeked, _ := body.reader.Peek(512)
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(peeked))
+	}
+
+	if !f.allowed(contentType) {
+		body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrContentType, contentType)
+	}
+
+	return &Response{
+		Body:          body,
+		ContentType:   contentType,
+		ContentLength: resp.ContentLength,
+		URL:           resp.Request.URL,
+	}, nil
+}
+
+// Requests rawURL and reads the whole body
+func (f *Fetcher) Fetch(rawURL string) ([]byte, *Response, error) {
+	resp, err := f.Get(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, resp, nil
+}
+
+func (f *Fetcher) allowed(contentType string) bool {
+	if len(f.ContentTypes) == 0 {
+		return true
+	}
+
+	for _, allowed := range f.ContentTypes {
+		if contentType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(contentType, allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Response body that fails once more than its size limit is read
+type limitedBody struct {
+	reader *bufio.Reader
+	closer io.Closer
+	cancel context.CancelFunc
+	left   int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell apart bodies that are exactly at it
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+
+	n, err := b.reader.Read(p)
+	b.left -= int64(n)
+
+	if b.left < 0 {
+		return n, ErrTooLarge
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	defer b.cancel()
+
+	return b.closer.Close()
+}
@@ -0,0 +1,220 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// 1x1 transparent PNG
+var pngData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x0a, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae,
+	0x42, 0x60, 0x82,
+}
+
+func TestPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"1.1.1.1":              true,
+		"2606:4700:4700::1111": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.0.0.1":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"0.0.0.0":              false,
+		"0.1.2.3":              false,
+		"100.64.0.1":           false,
+		"192.0.0.8":            false,
+		"198.18.0.1":           false,
+		"198.19.255.255":       false,
+		"240.0.0.1":            false,
+		"255.255.255.255":      false,
+		"64:ff9b::a00:1":       false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:10.0.0.1":      false,
+	}
+
+	for address, want := range tests {
+		if got := publicIP(net.ParseIP(address)); got != want {
+			t.Errorf("publicIP(%s) = %t, want %t", address, got, want)
+		}
+	}
+}
+
+func TestGetBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	_, err := (&Fetcher{}).Get(server.URL)
+
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Get of a loopback server returned %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestGetRejectsInvalidURLs(t *testing.T) {
+	for _, rawURL := range []string{"file:///etc/passwd", "gopher://example.com", "http://", "not a url"} {
+		if _, err := (&Fetcher{}).Get(rawURL); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Get(%q) returned %v, want ErrInvalidURL", rawURL, err)
+		}
+	}
+}
+
+func TestGetRedirectLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(r.URL.Query().Get("hops"))
+
+		if hops == 0 {
+			w.Write([]byte("done"))
+			return
+		}
+
+		http.Redirect(w, r, "/?hops="+strconv.Itoa(hops-1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{MaxRedirects: 2, AllowPrivate: true}
+
+	data, _, err := fetcher.Fetch(server.URL + "/?hops=2")
+
+	if err != nil || string(data) != "done" {
+		t.Fatalf("Fetch within the redirect limit returned %q, %v", data, err)
+	}
+
+	if _, err := fetcher.Get(server.URL + "/?hops=3"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("Get past the redirect limit returned %v, want ErrTooManyRedirects", err)
+	}
+}
+
+func TestGetRedirectToOtherScheme(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	defer server.Close()
+
+	if _, err := (&Fetcher{AllowPrivate: true}).Get(server.URL); !errors.Is(err, ErrInvalidURL) {
+		t.Fatalf("Get redirected to ftp returned %v, want ErrInvalidURL", err)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := (&Fetcher{AllowPrivate: true}).Get(server.URL)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Get of a missing page returned %v, want a 404 StatusError", err)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Without a Content-Length the limit can only be enforced while reading
+		if r.URL.Query().Get("chunked") != "" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(body[:50])
+			w.(http.Flusher).Flush()
+			w.Write(body[50:])
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	t.Run("at limit", func(t *testing.T) {
+		data, _, err := (&Fetcher{MaxSize: 100, AllowPrivate: true}).Fetch(server.URL)
+
+		if err != nil || len(data) != 100 {
+			t.Fatalf("Fetch at the limit returned %v bytes, %v", len(data), err)
+		}
+	})
+
+	t.Run("content length over limit", func(t *testing.T) {
+		if _, err := (&Fetcher{MaxSize: 99, AllowPrivate: true}).Get(server.URL); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("Get over the limit returned %v, want ErrTooLarge", err)
+		}
+	})
+
+	t.Run("chunked body over limit", func(t *testing.T) {
+		resp, err := (&Fetcher{MaxSize: 60, AllowPrivate: true}).Get(server.URL + "/?chunked=1")
+
+		if err != nil {
+			t.Fatalf("Get of a chunked body failed early: %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+
+		if !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("Reading past the limit returned %v, want ErrTooLarge", err)
+		}
+
+		if len(data) > 61 {
+			t.Fatalf("Read %v bytes past a limit of 60", len(data))
+		}
+	})
+}
+
+func TestContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/sniffed":
+			// Sniffed as image/png
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngData)
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{ContentTypes: []string{"image/"}, AllowPrivate: true}
+
+	if _, err := fetcher.Get(server.URL + "/text"); !errors.Is(err, ErrContentType) {
+		t.Errorf("Get of html returned %v, want ErrContentType", err)
+	}
+
+	for _, path := range []string{"/png", "/sniffed"} {
+		_, resp, err := fetcher.Fetch(server.URL + path)
+
+		if err != nil {
+			t.Errorf("Fetch of %s failed: %v", path, err)
+			continue
+		}
+
+		if resp.ContentType != "image/png" {
+			t.Errorf("Fetch of %s has content type %q, want image/png", path, resp.ContentType)
+		}
+	}
+
+	exact := &Fetcher{ContentTypes: []string{"image/jpeg"}, AllowPrivate: true}
+
+	if _, err := exact.Get(server.URL + "/png"); !errors.Is(err, ErrContentType) {
+		t.Errorf("Get of a png allowing only jpeg returned %v, want ErrContentType", err)
+	}
+}
@@ -0,0 +1,175 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A parsed text with `{variable}` placeholders and `{if variable}...{else}...{end}` conditionals.
+// `{if !variable}` negates a conditional, `{{` and `}}` render literal braces.
+type Template struct {
+	nodes []node
+}
+
+type node struct {
+	text      string
+	variable  string     // Placeholder if set
+	condition *condition // Conditional if set
+}
+
+type condition struct {
+	variable  string
+	negate    bool
+	then      []node
+	otherwise []node
+	inElse    bool // Whether parsing reached {else}
+}
+
+// Parses text, only allowing placeholders and conditionals for the given variables
+func Parse(text string, variables []string) (*Template, error) {
+	allowed := make(map[string]bool, len(variables))
+	for _, variable := range variables {
+		allowed[variable] = true
+	}
+
+	root := &condition{}
+	stack := []*condition{root}
+	literal := strings.Builder{}
+
+	// Appends to the branch of the innermost conditional being parsed
+	appendNode := func(n node) {
+		current := stack[len(stack)-1]
+
+		if current.inElse {
+			current.otherwise = append(current.otherwise, n)
+		} else {
+			current.then = append(current.then, n)
+		}
+	}
+
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			appendNode(node{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	checkVariable := func(variable string) error {
+		if !allowed[variable] {
+			return fmt.Errorf("unknown placeholder {%s}, available placeholders: %s", variable, Placeholders(variables))
+		}
+		return nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case c == '{' && i+1 < len(text) && text[i+1] == '{':
+			literal.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(text) && text[i+1] == '}':
+			literal.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("placeholder at position %v is never closed", i+1)
+			}
+
+			tag := strings.TrimSpace(text[i+1 : i+end])
+			i += end
+
+			flushLiteral()
+
+			switch {
+			case strings.HasPrefix(tag, "if "):
+				variable := strings.TrimSpace(strings.TrimPrefix(tag, "if "))
+				negate := strings.HasPrefix(variable, "!")
+				variable = strings.TrimPrefix(variable, "!")
+
+				if err := checkVariable(variable); err != nil {
+					return nil, err
+				}
+
+				cond := &condition{variable: variable, negate: negate}
+				appendNode(node{condition: cond})
+				stack = append(stack, cond)
+			case tag == "else":
+				current := stack[len(stack)-1]
+
+				if len(stack) == 1 || current.inElse {
+					return nil, fmt.Errorf("unexpected {else} at position %v", i-end+1)
+				}
+
+				current.inElse = true
+			case tag == "end":
+				if len(stack) == 1 {
+					return nil, fmt.Errorf("unexpected {end} at position %v", i-end+1)
+				}
+
+				stack = stack[:len(stack)-1]
+			default:
+				if err := checkVariable(tag); err != nil {
+					return nil, err
+				}
+
+				appendNode(node{variable: tag})
+			}
+		case c == '}':
+			return nil, fmt.Errorf("unexpected } at position %v, use }} for a literal brace", i+1)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+
+	flushLiteral()
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("{if %s} is never closed with {end}", stack[len(stack)-1].variable)
+	}
+
+	return &Template{nodes: root.then}, nil
+}
+
+// Renders the template, placeholders missing from values render empty
+func (t *Template) Render(values map[string]string) string {
+	result := strings.Builder{}
+
+	render(&result, t.nodes, values)
+
+	return result.String()
+}
+
+func render(result *strings.Builder, nodes []node, values map[string]string) {
+	for _, n := range nodes {
+		switch {
+		case n.condition != nil:
+			if Truthy(values[n.condition.variable]) != n.condition.negate {
+				render(result, n.condition.then, values)
+			} else {
+				render(result, n.condition.otherwise, values)
+			}
+		case n.variable != "":
+			result.WriteString(values[n.variable])
+		default:
+			result.WriteString(n.text)
+		}
+	}
+}
+
+// Whether a value passes an {if} conditional
+func Truthy(value string) bool {
+	return value != "" && value != "0" && value != "false"
+}
+
+// Formats variables as a list of placeholders, for help and error messages
+func Placeholders(variables []string) string {
+	placeholders := make([]string, len(variables))
+
+	for i, variable := range variables {
+		placeholders[i] = fmt.Sprintf("{%s}", variable)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
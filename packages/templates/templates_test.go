@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+var testVariables = []string{"user", "server", "inviter", "count"}
+
+func TestRender(t *testing.T) {
+	values := map[string]string{
+		"user":   "Kodachi",
+		"server": "Gophers",
+		"count":  "0",
+	}
+
+	tests := map[string]string{
+		// Placeholders, unknown values render empty
+		"":                           "",
+		"Welcome!":                   "Welcome!",
+		"Welcome {user} to {server}": "Welcome Kodachi to Gophers",
+		"{ user }":                   "Kodachi",
+		"{inviter}":                  "",
+
+		// Conditionals
+		"{if user}hi {user}{end}":                  "hi Kodachi",
+		"{if inviter}by {inviter}{else}alone{end}": "alone",
+		"{if !inviter}no inviter{end}":             "no inviter",
+		"{if !user}no user{else}{user}{end}":       "Kodachi",
+		"{if count}{count} joins{else}first{end}":  "first",
+
+		// Nested conditionals
+		"{if user}{if inviter}both{else}only user{end}{end}":               "only user",
+		"{if user}a{if server}b{if !inviter}c{end}d{end}e{end}":            "abcde",
+		"{if inviter}{if user}hidden{end}{else}{if server}shown{end}{end}": "shown",
+
+		// Escapes
+		"{{user}}":           "{user}",
+		"{{{user}}}":         "{Kodachi}",
+		"{{if user}}{{end}}": "{if user}{end}",
+		"}}{{":               "}{",
+	}
+
+	for text, want := range tests {
+		template, err := Parse(text, testVariables)
+
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+			continue
+		}
+
+		if got := template.Render(values); got != want {
+			t.Errorf("Parse(%q).Render() = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// Error messages are shown to users, so each case checks a part of it
+	tests := map[string]string{
+		// Unknown variables
+		"{nickname}":           "unknown placeholder {nickname}",
+		"{}":                   "unknown placeholder {}",
+		"{if nickname}x{end}":  "unknown placeholder {nickname}",
+		"{if !nickname}x{end}": "unknown placeholder {nickname}",
+
+		// Unclosed tags and conditionals
+		"Hello {user":                "never closed",
+		"{if user}hi":                "{if user} is never closed",
+		"{if user}{if server}x{end}": "{if user} is never closed",
+		"{if user}x{else}y":          "{if user} is never closed",
+
+		// Stray {end}, {else} and braces
+		"x{end}":                        "unexpected {end}",
+		"{if user}x{end}{end}":          "unexpected {end}",
+		"x{else}y":                      "unexpected {else}",
+		"{if user}a{else}b{else}c{end}": "unexpected {else}",
+		"a } b":                         "unexpected }",
+		"{user}}":                       "unexpected }",
+	}
+
+	for text, want := range tests {
+		_, err := Parse(text, testVariables)
+
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error containing %q", text, want)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) returned %q, want an error containing %q", text, err, want)
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	tests := map[string]bool{
+		"":      false,
+		"0":     false,
+		"false": false,
+		"1":     true,
+		"true":  true,
+		"name":  true,
+	}
+
+	for value, want := range tests {
+		if got := Truthy(value); got != want {
+			t.Errorf("Truthy(%q) = %t, want %t", value, got, want)
+		}
+	}
+}
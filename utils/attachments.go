@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/packages/blobs"
+	"kodachi/packages/fetcher"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+var ErrWelcomeAttachmentRemoved = errors.New("the welcome attachment can no longer be downloaded and was removed, upload it again with /config welcome_message_attachment")
+
+// Welcome messages may be sent to any server or in DMs
+const MaxWelcomeAttachmentSize = DefaultUploadLimit
+
+// Accepted welcome attachment types, with the extension used when the original one doesn't match
+var welcomeAttachmentTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"video/mp4":  ".mp4",
+}
+
+var welcomeAttachmentFetcher = &fetcher.Fetcher{
+	MaxSize:      MaxWelcomeAttachmentSize,
+	ContentTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp", "video/mp4"},
+}
+
+// Welcome attachment kept in the blob store
+type StoredAttachment struct {
+	Key         string
+	Name        string
+	ContentType string
+}
+
+// Downloads a welcome attachment once and keeps it in the blob store.
+// name is the original file name, the URL's is used if it's empty.
+func StoreWelcomeAttachment(attachmentURL, name string) (StoredAttachment, error) {
+	data, resp, err := welcomeAttachmentFetcher.Fetch(attachmentURL)
+
+	switch {
+	case errors.Is(err, fetcher.ErrTooLarge):
+		return StoredAttachment{}, fmt.Errorf("the attachment is larger than %v MB", MaxWelcomeAttachmentSize>>20)
+	case errors.Is(err, fetcher.ErrContentType):
+		return StoredAttachment{}, fmt.Errorf("%w, use a PNG, JPEG, GIF, WebP or MP4 file", err)
+	case err != nil:
+		return StoredAttachment{}, fmt.Errorf("couldn't download the attachment: %w", err)
+	}
+
+	// Sniffed rather than trusting the Content-Type header
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+
+	defaultExtension, ok := welcomeAttachmentTypes[contentType]
+	if !ok {
+		return StoredAttachment{}, fmt.Errorf("%s files aren't supported, use a PNG, JPEG, GIF, WebP or MP4 file", contentType)
+	}
+
+	if name == "" {
+		name = path.Base(resp.URL.Path)
+	}
+
+	key, err := blobs.Put(data)
+	if err != nil {
+		return StoredAttachment{}, err
+	}
+
+	return StoredAttachment{
+		Key:         key,
+		Name:        "welcome" + attachmentExtension(name, contentType, defaultExtension),
+		ContentType: contentType,
+	}, nil
+}
+
+// Returns the extension of name if it matches contentType, e.g. keeping ".jpeg" over ".jpg"
+func attachmentExtension(name, contentType, defaultExtension string) string {
+	extension := strings.ToLower(path.Ext(name))
+
+	if extensionType, _, _ := mime.ParseMediaType(mime.TypeByExtension(extension)); extension != "" && extensionType == contentType {
+		return extension
+	}
+
+	return defaultExtension
+}
+
+// Returns the welcome attachment of a guild, or nil if it has none.
+// Attachments configured before they were stored are downloaded and stored on first use.
+func WelcomeAttachment(db *gorm.DB, config models.Config) (*discordgo.File, error) {
+	if config.WelcomeMessageAttachmentKey == "" {
+		if config.WelcomeMessageAttachmentURL == "" {
+			return nil, nil
+		}
+
+		attachment, err := StoreWelcomeAttachment(config.WelcomeMessageAttachmentURL, "")
+
+		// Expired or deleted, retrying on every join would only wait on the download again
+		var statusErr *fetcher.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 {
+			result := db.Model(&models.Config{}).Where(&models.Config{GuildId: config.GuildId}).Updates(map[string]interface{}{
+				"welcome_message_attachment_url":     "",
+				"welcome_message_attachment_removed": err.Error(),
+			})
+
+			if result.Error != nil {
+				return nil, result.Error
+			}
+
+			return nil, ErrWelcomeAttachmentRemoved
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result := db.Model(&models.Config{}).Where(&models.Config{GuildId: config.GuildId}).Updates(&models.Config{
+			WelcomeMessageAttachmentKey:         attachment.Key,
+			WelcomeMessageAttachmentName:        attachment.Name,
+			WelcomeMessageAttachmentContentType: attachment.ContentType,
+		})
+
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		config.WelcomeMessageAttachmentKey = attachment.Key
+		config.WelcomeMessageAttachmentName = attachment.Name
+		config.WelcomeMessageAttachmentContentType = attachment.ContentType
+	}
+
+	data, err := blobs.Read(config.WelcomeMessageAttachmentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordgo.File{
+		Name:        config.WelcomeMessageAttachmentName,
+		ContentType: config.WelcomeMessageAttachmentContentType,
+		Reader:      bytes.NewReader(data),
+	}, nil
+}
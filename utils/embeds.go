@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/packages/templates"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Embed configured by a guild, with placeholders in its title, description and footer
+type EmbedTemplate struct {
+	Title       string
+	Description string
+	Color       int
+	ImageURL    string
+	Footer      string
+}
+
+func WelcomeEmbedTemplate(config models.Config) EmbedTemplate {
+	return EmbedTemplate{
+		Title:       config.WelcomeEmbedTitle,
+		Description: config.WelcomeEmbedDescription,
+		Color:       config.WelcomeEmbedColor,
+		ImageURL:    config.WelcomeEmbedImageURL,
+		Footer:      config.WelcomeEmbedFooter,
+	}
+}
+
+func GoodbyeEmbedTemplate(config models.Config) EmbedTemplate {
+	return EmbedTemplate{
+		Title:       config.GoodbyeEmbedTitle,
+		Description: config.GoodbyeEmbedDescription,
+		Color:       config.GoodbyeEmbedColor,
+		ImageURL:    config.GoodbyeEmbedImageURL,
+		Footer:      config.GoodbyeEmbedFooter,
+	}
+}
+
+// Whether any part of the embed is configured
+func (e EmbedTemplate) IsSet() bool {
+	return e.Title != "" || e.Description != "" || e.ImageURL != "" || e.Footer != ""
+}
+
+// Checks that the templated parts only use the given variables
+func (e EmbedTemplate) Validate(variables []string) error {
+	_, err := e.Render(variables, map[string]string{})
+
+	return err
+}
+
+func (e EmbedTemplate) Render(variables []string, values map[string]string) (*discordgo.MessageEmbed, error) {
+	render := func(part, text string) (string, error) {
+		template, err := templates.Parse(text, variables)
+		if err != nil {
+			return "", fmt.Errorf("embed %s: %w", part, err)
+		}
+
+		return template.Render(values), nil
+	}
+
+	var err error
+
+	embed := &discordgo.MessageEmbed{
+		Color: e.Color,
+	}
+
+	if embed.Title, err = render("title", e.Title); err != nil {
+		return nil, err
+	}
+
+	if embed.Description, err = render("description", e.Description); err != nil {
+		return nil, err
+	}
+
+	if e.Footer != "" {
+		footer, err := render("footer", e.Footer)
+		if err != nil {
+			return nil, err
+		}
+
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+
+	if e.ImageURL != "" {
+		embed.Image = &discordgo.MessageEmbedImage{URL: e.ImageURL}
+	}
+
+	return embed, nil
+}
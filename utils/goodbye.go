@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"kodachi/bot/models"
+	"kodachi/packages/templates"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var GoodbyeTemplateVariables = []string{"mention", "username", "user_id", "server", "member_count", "account_age", "bot", "moderator", "reason"}
+
+// Why a member left a guild
+const (
+	GoodbyeLeave = "leave"
+	GoodbyeKick  = "kick"
+	GoodbyeBan   = "ban"
+)
+
+// How a member was removed from a guild, moderator is nil if they left by themselves
+type GoodbyeReason struct {
+	Kind      string
+	Moderator *discordgo.User
+	Reason    string
+}
+
+func ParseGoodbyeTemplate(text string) (*templates.Template, error) {
+	return templates.Parse(text, GoodbyeTemplateVariables)
+}
+
+// Returns the goodbye message configured for kind, falling back to the generic one
+func GoodbyeMessage(config models.Config, kind string) string {
+	switch {
+	case kind == GoodbyeKick && config.GoodbyeKickMessage != "":
+		return config.GoodbyeKickMessage
+	case kind == GoodbyeBan && config.GoodbyeBanMessage != "":
+		return config.GoodbyeBanMessage
+	}
+
+	return config.GoodbyeMessage
+}
+
+// Returns placeholder values for saying goodbye to user
+func GoodbyeTemplateValues(s *discordgo.Session, guildId string, user *discordgo.User, reason GoodbyeReason) map[string]string {
+	values := map[string]string{
+		"mention":  fmt.Sprintf("<@%s>", user.ID),
+		"username": user.Username,
+		"user_id":  user.ID,
+		"bot":      strconv.FormatBool(user.Bot),
+		"reason":   reason.Reason,
+	}
+
+	if reason.Moderator != nil {
+		values["moderator"] = reason.Moderator.Username
+	}
+
+	if created, err := discordgo.SnowflakeTimestamp(user.ID); err == nil {
+		values["account_age"] = FormatDuration(time.Since(created))
+	}
+
+	if guild, err := welcomeGuild(s, guildId); err == nil {
+		values["server"] = guild.Name
+		values["member_count"] = strconv.Itoa(guild.MemberCount)
+	}
+
+	return values
+}
+
+// Renders the goodbye message and embed configured for a guild, the embed is nil if not configured
+func RenderGoodbye(s *discordgo.Session, config models.Config, user *discordgo.User, reason GoodbyeReason) (string, *discordgo.MessageEmbed, error) {
+	values := GoodbyeTemplateValues(s, config.GuildId, user, reason)
+
+	template, err := ParseGoodbyeTemplate(GoodbyeMessage(config, reason.Kind))
+	if err != nil {
+		return "", nil, fmt.Errorf("goodbye message: %w", err)
+	}
+
+	embedTemplate := GoodbyeEmbedTemplate(config)
+
+	if !embedTemplate.IsSet() {
+		return template.Render(values), nil, nil
+	}
+
+	embed, err := embedTemplate.Render(GoodbyeTemplateVariables, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+		URL: user.AvatarURL("256"),
+	}
+
+	return template.Render(values), embed, nil
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type cachedInvite struct {
+	InviterId string
+	Uses      int
+	MaxUses   int
+}
+
+// Invite uses by code, by guild
+var inviteCache = struct {
+	sync.Mutex
+	guilds map[string]map[string]cachedInvite
+	joins  map[string]*sync.Mutex // Held while comparing a join of the guild
+}{guilds: map[string]map[string]cachedInvite{}, joins: map[string]*sync.Mutex{}}
+
+func guildJoinsMutex(guildId string) *sync.Mutex {
+	inviteCache.Lock()
+	defer inviteCache.Unlock()
+
+	mutex, ok := inviteCache.joins[guildId]
+
+	if !ok {
+		mutex = &sync.Mutex{}
+		inviteCache.joins[guildId] = mutex
+	}
+
+	return mutex
+}
+
+func fetchGuildInvites(s *discordgo.Session, guildId string) (map[string]cachedInvite, error) {
+	invites, err := s.GuildInvites(guildId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cached := make(map[string]cachedInvite, len(invites))
+
+	for _, invite := range invites {
+		cachedInvite := cachedInvite{Uses: invite.Uses, MaxUses: invite.MaxUses}
+
+		if invite.Inviter != nil {
+			cachedInvite.InviterId = invite.Inviter.ID
+		}
+
+		cached[invite.Code] = cachedInvite
+	}
+
+	return cached, nil
+}
+
+// Replaces the cached invite uses of a guild, requires the Manage Server permission
+func CacheGuildInvites(s *discordgo.Session, guildId string) error {
+	invites, err := fetchGuildInvites(s, guildId)
+
+	if err != nil {
+		return err
+	}
+
+	inviteCache.Lock()
+	defer inviteCache.Unlock()
+
+	inviteCache.guilds[guildId] = invites
+
+	return nil
+}
+
+func CacheInvite(guildId string, invite *discordgo.Invite) {
+	inviteCache.Lock()
+	defer inviteCache.Unlock()
+
+	invites, ok := inviteCache.guilds[guildId]
+
+	// Uses can't be compared until the other invites are cached too
+	if !ok {
+		return
+	}
+
+	cached := cachedInvite{Uses: invite.Uses, MaxUses: invite.MaxUses}
+
+	if invite.Inviter != nil {
+		cached.InviterId = invite.Inviter.ID
+	}
+
+	invites[invite.Code] = cached
+}
+
+// Removes a deleted invite from the cache, unless the join it was used up by hasn't been compared yet
+func UncacheInvite(guildId, code string) {
+	inviteCache.Lock()
+	defer inviteCache.Unlock()
+
+	if invite, ok := inviteCache.guilds[guildId][code]; ok && !invite.usedUpByNextJoin() {
+		delete(inviteCache.guilds[guildId], code)
+	}
+}
+
+func (invite cachedInvite) usedUpByNextJoin() bool {
+	return invite.MaxUses > 0 && invite.Uses+1 >= invite.MaxUses
+}
+
+// Finds the invite a member just joined with by comparing invite uses with the cache.
+// Returns an empty code if it can't be told apart, e.g. for vanity URLs or several simultaneous joins.
+func FindUsedInvite(s *discordgo.Session, guildId string) (code string, inviterId string, err error) {
+	// Held while fetching so that simultaneous joins of a guild are compared one after the other,
+	// without waiting on the joins of other guilds
+	joinsMutex := guildJoinsMutex(guildId)
+	joinsMutex.Lock()
+	defer joinsMutex.Unlock()
+
+	invites, err := fetchGuildInvites(s, guildId)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	// Only the comparison, the cached invites may be updated by invite events
+	inviteCache.Lock()
+	defer inviteCache.Unlock()
+
+	previous, ok := inviteCache.guilds[guildId]
+	inviteCache.guilds[guildId] = invites
+
+	if !ok {
+		return "", "", nil
+	}
+
+	used := []string{}
+
+	for code, invite := range invites {
+		if invite.Uses > previous[code].Uses {
+			used = append(used, code)
+		}
+	}
+
+	// Invites that reached their max uses are deleted instead of counted
+	if len(used) == 0 {
+		for code, invite := range previous {
+			if _, ok := invites[code]; !ok && invite.usedUpByNextJoin() {
+				used = append(used, code)
+			}
+		}
+	}
+
+	if len(used) != 1 {
+		return "", "", nil
+	}
+
+	if invite, ok := invites[used[0]]; ok {
+		return used[0], invite.InviterId, nil
+	}
+
+	return used[0], previous[used[0]].InviterId, nil
+}
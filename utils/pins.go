@@ -0,0 +1,417 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"kodachi/bot/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+// Pinning errors, worded to be shown to users
+var (
+	ErrPinsChannelMissing  = errors.New("Configured pins channel does not exist.")
+	ErrPinsWebhooks        = errors.New("Failed to get guild webhooks, check bot permissions.")
+	ErrPinsWebhookCreate   = errors.New("Failed to create a webhook, please create one yourself or check bot permissions.")
+	ErrPinsSend            = errors.New("Failed to send the pinned message, please try again.")
+	ErrPinsFromPinsChannel = errors.New("Messages in pins channels can't be pinned.")
+)
+
+// Returns the text a pinned message is indexed by, its content followed by its attachment names
+func PinnedMessageContent(message *discordgo.Message) string {
+	lines := []string{}
+
+	if message.Content != "" {
+		lines = append(lines, message.Content)
+	}
+
+	for _, attachment := range message.Attachments {
+		lines = append(lines, "["+attachment.Filename+"]")
+	}
+
+	for _, sticker := range message.StickerItems {
+		lines = append(lines, "[Sticker: "+sticker.Name+"]")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Returns the webhook of the bot in a pins channel, creating it if there is none
+func PinsWebhook(s *discordgo.Session, channelId string) (*discordgo.Webhook, error) {
+	if _, err := s.Channel(channelId); err != nil {
+		return nil, ErrPinsChannelMissing
+	}
+
+	webhooks, err := s.ChannelWebhooks(channelId)
+
+	if err != nil {
+		return nil, ErrPinsWebhooks
+	}
+
+	for _, webhook := range webhooks {
+		if webhook.ApplicationID == s.State.User.ID {
+			return webhook, nil
+		}
+	}
+
+	webhook, err := s.WebhookCreate(channelId, fmt.Sprintf("Pins [%s]", s.State.User.Username), "")
+
+	if err != nil {
+		return nil, ErrPinsWebhookCreate
+	}
+
+	return webhook, nil
+}
+
+// Not declared by discordgo yet
+const stickerFormatGIF discordgo.StickerFormat = 4
+
+// Returns the embeds of a pinned message copy: those of the message, its stickers as images,
+// and one with the message it replied to, who sent or pinned it and when it was sent
+func PinnedMessageEmbeds(message *discordgo.Message, pinnedMessage models.PinnedMessage) []*discordgo.MessageEmbed {
+	lines := []string{}
+
+	if message.Type == discordgo.MessageTypeReply {
+		if reply := message.ReferencedMessage; reply != nil {
+			quote := PinnedMessageContent(reply)
+			if quote == "" {
+				quote = "*No text*"
+			}
+
+			lines = append(lines,
+				fmt.Sprintf("Replying to **%s** [Jump](%s)", reply.Author.Username, MessageURL(pinnedMessage.GuildId, reply.ChannelID, reply.ID)),
+				"> "+strings.ReplaceAll(Truncate(quote, 300), "\n", "\n> "),
+			)
+		} else {
+			lines = append(lines, "Replying to a deleted message")
+		}
+	}
+
+	for _, attachment := range LinkedAttachments(message, pinnedMessage) {
+		lines = append(lines, fmt.Sprintf("📎 [%s](%s) (%.1f MB)", attachment.Filename, attachment.URL, float64(attachment.Size)/(1<<20)))
+	}
+
+	stickerEmbeds := []*discordgo.MessageEmbed{}
+
+	for _, sticker := range message.StickerItems {
+		extension := "png"
+
+		switch sticker.FormatType {
+		case stickerFormatGIF:
+			extension = "gif"
+		// Lottie stickers can't be shown as images
+		case discordgo.StickerFormatTypeLottie:
+			lines = append(lines, fmt.Sprintf("*Sticker: %s*", sticker.Name))
+			continue
+		}
+
+		stickerEmbeds = append(stickerEmbeds, &discordgo.MessageEmbed{
+			Image: &discordgo.MessageEmbedImage{
+				URL: fmt.Sprintf("https://media.discordapp.net/stickers/%s.%s", sticker.ID, extension),
+			},
+		})
+	}
+
+	footer := "Pinned"
+	switch {
+	case !pinnedMessage.AsAuthor:
+		footer = "Sent by " + pinnedMessage.AuthorName
+	case pinnedMessage.PinnerName != "":
+		footer = "Pinned by " + pinnedMessage.PinnerName
+	case pinnedMessage.Stars > 0:
+		footer = "Pinned from the starboard"
+	}
+
+	contextEmbed := &discordgo.MessageEmbed{
+		Description: Truncate(strings.Join(lines, "\n"), 4096),
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+		Timestamp:   message.Timestamp.Format(time.RFC3339),
+	}
+
+	// Messages have at most 10 embeds, the message's own are dropped first
+	messageEmbeds := message.Embeds
+	if limit := 10 - len(stickerEmbeds) - 1; len(messageEmbeds) > limit {
+		messageEmbeds = messageEmbeds[:limit]
+	}
+
+	embeds := make([]*discordgo.MessageEmbed, 0, len(messageEmbeds)+len(stickerEmbeds)+1)
+	embeds = append(embeds, messageEmbeds...)
+	embeds = append(embeds, stickerEmbeds...)
+
+	return append(embeds, contextEmbed)
+}
+
+// Returns the attachments of message that were linked in its copy instead of uploaded
+func LinkedAttachments(message *discordgo.Message, pinnedMessage models.PinnedMessage) []*discordgo.MessageAttachment {
+	linked := []*discordgo.MessageAttachment{}
+
+	if pinnedMessage.LinkedAttachmentIds == "" {
+		return linked
+	}
+
+	ids := strings.Split(pinnedMessage.LinkedAttachmentIds, ",")
+
+	// Attachments removed from the original by an edit are left out
+	for _, attachment := range message.Attachments {
+		for _, id := range ids {
+			if attachment.ID == id {
+				linked = append(linked, attachment)
+			}
+		}
+	}
+
+	return linked
+}
+
+// Returns the buttons under a pinned message copy, with the star count if it was pinned by the starboard
+func PinnedMessageComponents(guildId string, message *discordgo.Message, stars int, emoji string) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label: "Jump",
+			Style: discordgo.LinkButton,
+			URL:   MessageURL(guildId, message.ChannelID, message.ID),
+		},
+	}
+
+	if stars > 0 {
+		buttons = append(buttons, starsButton(message.ID, stars, emoji))
+	}
+
+	return append(message.Components, discordgo.ActionsRow{Components: buttons})
+}
+
+// Returns the buttons under the copy of a deleted message, which can no longer be jumped to
+func DeletedPinnedMessageComponents(pinnedMessage models.PinnedMessage, emoji string) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Original deleted",
+			Style:    discordgo.SecondaryButton,
+			CustomID: CustomID("pins_deleted", pinnedMessage.MessageId),
+			Disabled: true,
+		},
+	}
+
+	if pinnedMessage.Stars > 0 {
+		buttons = append(buttons, starsButton(pinnedMessage.MessageId, pinnedMessage.Stars, emoji))
+	}
+
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+func starsButton(messageId string, stars int, emoji string) discordgo.Button {
+	return discordgo.Button{
+		Label:    fmt.Sprint(stars),
+		Emoji:    StarboardButtonEmoji(emoji),
+		Style:    discordgo.SecondaryButton,
+		CustomID: CustomID("starboard_count", messageId),
+		Disabled: true,
+	}
+}
+
+// Returns the pins channel of messages from channelId, routed by the channel, its parent channel or its category,
+// falling back to the pins channel of the guild. Empty if there is none.
+func PinsChannel(db *gorm.DB, s *discordgo.Session, config models.Config, channelId string) (string, error) {
+	if channelId == config.PinsChannelId {
+		return "", ErrPinsFromPinsChannel
+	}
+
+	routes := []models.PinRoute{}
+
+	result := db.Where(&models.PinRoute{GuildId: config.GuildId}).Find(&routes)
+
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	// {"SourceId": PinsChannelId}
+	destinations := make(map[string]string, len(routes))
+
+	for _, route := range routes {
+		if channelId == route.PinsChannelId {
+			return "", ErrPinsFromPinsChannel
+		}
+
+		destinations[route.SourceId] = route.PinsChannelId
+	}
+
+	// Threads are routed by their channel and category, channels by their category
+	sourceId := channelId
+
+	for depth := 0; depth < 3 && sourceId != "" && len(destinations) > 0; depth++ {
+		if pinsChannelId, ok := destinations[sourceId]; ok {
+			return pinsChannelId, nil
+		}
+
+		channel, err := s.State.Channel(sourceId)
+
+		if err != nil {
+			channel, err = s.Channel(sourceId)
+
+			if err != nil {
+				break
+			}
+		}
+
+		sourceId = channel.ParentID
+	}
+
+	return config.PinsChannelId, nil
+}
+
+// Copies message to pinsChannelId and records it. pinner is nil for messages pinned by the starboard.
+func PinMessage(db *gorm.DB, s *discordgo.Session, config models.Config, pinsChannelId string, message *discordgo.Message, pinner *discordgo.User, stars int) (*models.PinnedMessage, error) {
+	webhook, err := PinsWebhook(s, pinsChannelId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pinnedMessage := models.PinnedMessage{
+		GuildId:       config.GuildId,
+		ChannelId:     message.ChannelID,
+		MessageId:     message.ID,
+		AuthorId:      message.Author.ID,
+		AuthorName:    message.Author.Username,
+		PinsChannelId: pinsChannelId,
+		WebhookId:     webhook.ID,
+		Content:       PinnedMessageContent(message),
+		Stars:         stars,
+		// Messages without a pinner can only be sent as their author
+		AsAuthor: config.PinsAsAuthor || pinner == nil,
+	}
+
+	sender := message.Author
+
+	if pinner != nil {
+		pinnedMessage.PinnerId = pinner.ID
+		pinnedMessage.PinnerName = pinner.Username
+
+		if !pinnedMessage.AsAuthor {
+			sender = pinner
+		}
+	}
+
+	// Leaves room for the rest of the request
+	attachmentFiles := DownloadAttachments(message.Attachments, UploadLimit(s, config.GuildId)-1<<20)
+	defer attachmentFiles.Close()
+
+	linkedIds := make([]string, len(attachmentFiles.Skipped))
+	for index, attachment := range attachmentFiles.Skipped {
+		linkedIds[index] = attachment.ID
+	}
+
+	pinnedMessage.LinkedAttachmentIds = strings.Join(linkedIds, ",")
+
+	// Wait for the webhook message so that it can be recorded
+	webhookMessage, err := s.WebhookExecute(webhook.ID, webhook.Token, true, &discordgo.WebhookParams{
+		Username:   sender.Username,
+		AvatarURL:  sender.AvatarURL(""),
+		Content:    message.Content,
+		Embeds:     PinnedMessageEmbeds(message, pinnedMessage),
+		TTS:        message.TTS,
+		Files:      attachmentFiles.Files,
+		Components: PinnedMessageComponents(config.GuildId, message, stars, config.StarboardEmoji),
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Parse: []discordgo.AllowedMentionType{},
+		},
+	})
+
+	if err != nil {
+		log.Printf("An error occurred while sending pin message: %v", err)
+		return nil, ErrPinsSend
+	}
+
+	pinnedMessage.WebhookMessageId = webhookMessage.ID
+
+	result := db.Create(&pinnedMessage)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+
+		// A copy that isn't recorded couldn't be unpinned or found again
+		if err := s.WebhookMessageDelete(webhook.ID, webhook.Token, webhookMessage.ID); err != nil {
+			log.Printf("Failed to delete unrecorded pin message %v: %v", webhookMessage.ID, err)
+		}
+
+		return nil, ErrPinsSend
+	}
+
+	return &pinnedMessage, nil
+}
+
+// Normalizes an emoji as typed in a command, "<:name:id>" for custom emojis, to the form reactions are compared in
+func ParseStarboardEmoji(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "<") && strings.HasSuffix(text, ">") {
+		parts := strings.Split(strings.Trim(text, "<>"), ":")
+
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return "", false
+		}
+
+		return parts[1] + ":" + parts[2], true
+	}
+
+	if text == "" || strings.ContainsAny(text, " :<>") {
+		return "", false
+	}
+
+	return text, true
+}
+
+// Returns the starboard emoji as used in message components
+func StarboardButtonEmoji(emoji string) discordgo.ComponentEmoji {
+	if name, id, ok := strings.Cut(emoji, ":"); ok {
+		return discordgo.ComponentEmoji{Name: name, ID: id}
+	}
+
+	return discordgo.ComponentEmoji{Name: emoji}
+}
+
+// Edits the copy of a pinned message in the pins channel
+func EditPinnedMessage(s *discordgo.Session, pinnedMessage models.PinnedMessage, data *discordgo.WebhookEdit) error {
+	// Tokens of webhooks created by the bot are returned along with them
+	webhook, err := s.Webhook(pinnedMessage.WebhookId)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.WebhookMessageEdit(webhook.ID, webhook.Token, pinnedMessage.WebhookMessageId, data)
+
+	return err
+}
+
+// Deletes the copy of a pinned message and its record. The original is unpinned too, so that it isn't mirrored again.
+func DeletePinnedMessage(db *gorm.DB, s *discordgo.Session, pinnedMessage models.PinnedMessage) error {
+	webhook, err := s.Webhook(pinnedMessage.WebhookId)
+
+	if err == nil {
+		err = s.WebhookMessageDelete(webhook.ID, webhook.Token, pinnedMessage.WebhookMessageId)
+	}
+
+	// The copy or its webhook may already have been deleted by hand
+	var restErr *discordgo.RESTError
+	if err != nil && !(errors.As(err, &restErr) && restErr.Response.StatusCode == http.StatusNotFound) {
+		return err
+	}
+
+	if result := db.Delete(&pinnedMessage); result.Error != nil {
+		return result.Error
+	}
+
+	// Only pins mirrored by the bot, others may have been pinned natively on purpose
+	if pinnedMessage.NativelyPinned {
+		if err := s.ChannelMessageUnpin(pinnedMessage.ChannelId, pinnedMessage.MessageId); err != nil {
+			log.Printf("Failed to unpin original of %v: %v", pinnedMessage.MessageId, err)
+		}
+	}
+
+	return nil
+}
@@ -1,16 +1,30 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"kodachi/bot/models"
+	"kodachi/packages/fetcher"
 	"kodachi/packages/trees"
-	"net/http"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
 )
 
 // Convert month days to contain ordinal indicators
 func Ordinal(n int) string {
 	suffix := "th"
+
+	// 11th, 12th and 13th
+	if n%100 >= 11 && n%100 <= 13 {
+		return fmt.Sprintf("%v%s", n, suffix)
+	}
+
 	switch n % 10 {
 	case 1:
 		suffix = "st"
@@ -26,34 +40,259 @@ func MessageURL(guildId, channelId, messageId string) string {
 	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildId, channelId, messageId)
 }
 
-func AttachmentToFile(attachment discordgo.MessageAttachment) (discordgo.File, error) {
-	resp, err := http.Get(attachment.ProxyURL)
+// Returns the user ID of a raw ID or a <@ID>/<@!ID> mention
+func ParseUserID(value string) (string, bool) {
+	userId := strings.TrimSpace(value)
+	userId = strings.TrimPrefix(userId, "<@")
+	userId = strings.TrimPrefix(userId, "!")
+	userId = strings.TrimSuffix(userId, ">")
+
+	if _, err := strconv.ParseUint(userId, 10, 64); err != nil {
+		return "", false
+	}
+
+	return userId, true
+}
+
+// Sends a direct message to a user
+func SendDM(s *discordgo.Session, userId string, data *discordgo.MessageSend) error {
+	ch, err := s.UserChannelCreate(userId)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelMessageSendComplex(ch.ID, data)
+
+	return err
+}
+
+// Returns the roles of a guild from state, fetching them if the guild isn't cached
+func GuildRoles(s *discordgo.Session, guildId string) ([]*discordgo.Role, error) {
+	if guild, err := s.State.Guild(guildId); err == nil {
+		return guild.Roles, nil
+	}
+
+	return s.GuildRoles(guildId)
+}
+
+// Returns the position of the highest role of the bot in a guild
+func BotTopRolePosition(s *discordgo.Session, guildId string) (int, error) {
+	member, err := s.State.Member(guildId, s.State.User.ID)
+
+	if err != nil {
+		member, err = s.GuildMember(guildId, s.State.User.ID)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	roles, err := GuildRoles(s, guildId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	position := 0
+
+	for _, role := range roles {
+		for _, roleId := range member.Roles {
+			if role.ID == roleId && role.Position > position {
+				position = role.Position
+			}
+		}
+	}
+
+	return position, nil
+}
+
+// Computes the permissions of a member in a channel from the roles of the guild and the overwrites of the channel,
+// without needing the member in state
+func MemberChannelPermissions(guild *discordgo.Guild, channel *discordgo.Channel, member *discordgo.Member) int64 {
+	if member.User.ID == guild.OwnerID {
+		return discordgo.PermissionAll
+	}
+
+	var permissions int64
+
+	for _, role := range guild.Roles {
+		// The @everyone role has the ID of the guild
+		if role.ID == guild.ID {
+			permissions |= role.Permissions
+			continue
+		}
+
+		for _, roleId := range member.Roles {
+			if role.ID == roleId {
+				permissions |= role.Permissions
+				break
+			}
+		}
+	}
+
+	if permissions&discordgo.PermissionAdministrator != 0 {
+		return discordgo.PermissionAll
+	}
+
+	// Overwrites apply @everyone first, then roles together, then the member
+	var denies, allows int64
+
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.ID == guild.ID {
+			permissions &^= overwrite.Deny
+			permissions |= overwrite.Allow
+			continue
+		}
+
+		if overwrite.Type != discordgo.PermissionOverwriteTypeRole {
+			continue
+		}
+
+		for _, roleId := range member.Roles {
+			if overwrite.ID == roleId {
+				denies |= overwrite.Deny
+				allows |= overwrite.Allow
+				break
+			}
+		}
+	}
+
+	permissions &^= denies
+	permissions |= allows
+
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.Type == discordgo.PermissionOverwriteTypeMember && overwrite.ID == member.User.ID {
+			permissions &^= overwrite.Deny
+			permissions |= overwrite.Allow
+		}
+	}
+
+	return permissions
+}
+
+// Builds a component custom ID from a handler prefix and its arguments
+func CustomID(prefix string, args ...string) string {
+	return strings.Join(append([]string{prefix}, args...), ":")
+}
+
+// Splits a custom ID built with CustomID back into its prefix and arguments
+func ParseCustomID(customId string) (string, []string) {
+	parts := strings.Split(customId, ":")
+
+	return parts[0], parts[1:]
+}
+
+// Returns {"CustomID": Value} of every text input in a submitted modal
+func ModalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := make(map[string]string)
+
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+
+		for _, rowComponent := range row.Components {
+			if input, ok := rowComponent.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+
+	return values
+}
+
+// Discord's upload limit for servers without boosts and for DMs
+const DefaultUploadLimit = 25 << 20
+
+// Upload limits of a whole message by the boost tier of the guild
+var uploadLimits = map[discordgo.PremiumTier]int64{
+	discordgo.PremiumTierNone: DefaultUploadLimit,
+	discordgo.PremiumTier1:    DefaultUploadLimit,
+	discordgo.PremiumTier2:    50 << 20,
+	discordgo.PremiumTier3:    100 << 20,
+}
+
+// Fetches attachments to upload again, capped at the largest upload limit
+var attachmentFetcher = &fetcher.Fetcher{
+	Timeout: time.Minute,
+	MaxSize: uploadLimits[discordgo.PremiumTier3],
+}
+
+// Returns how many bytes can be uploaded to a guild in one message
+func UploadLimit(s *discordgo.Session, guildId string) int64 {
+	guild, err := s.State.Guild(guildId)
 
 	if err != nil {
-		return discordgo.File{}, err
+		guild, err = s.Guild(guildId)
+
+		if err != nil {
+			return uploadLimits[discordgo.PremiumTierNone]
+		}
 	}
 
-	return discordgo.File{
-		ContentType: attachment.ContentType,
-		Name:        attachment.Filename,
-		Reader:      resp.Body,
-	}, nil
+	if limit, ok := uploadLimits[guild.PremiumTier]; ok {
+		return limit
+	}
+
+	return uploadLimits[discordgo.PremiumTierNone]
+}
+
+// Attachments being downloaded to be uploaded again. Files are read from the downloads, which stay open until Close,
+// into the request body, which discordgo builds in memory.
+type AttachmentFiles struct {
+	Files   []*discordgo.File
+	Skipped []*discordgo.MessageAttachment // Over the size budget or failed to download
+	bodies  []io.Closer
 }
 
-func AttachmentsToFile(attachments []*discordgo.MessageAttachment) ([]*discordgo.File, error) {
-	files := make([]*discordgo.File, len(attachments))
+// Starts downloading attachments while their total size fits in budget, skipping those that don't.
+// At most budget bytes are read in total, even if the sizes reported by Discord are wrong.
+func DownloadAttachments(attachments []*discordgo.MessageAttachment, budget int64) *AttachmentFiles {
+	files := &AttachmentFiles{}
 
-	for i, attachment := range attachments {
-		file, err := AttachmentToFile(*attachment)
+	for _, attachment := range attachments {
+		// Smaller attachments after a skipped one may still fit
+		if int64(attachment.Size) > budget {
+			files.Skipped = append(files.Skipped, attachment)
+			continue
+		}
+
+		// Reading past the reported size fails the upload rather than buffering more than budget
+		limitedFetcher := *attachmentFetcher
+		limitedFetcher.MaxSize = int64(attachment.Size)
+
+		// A zero MaxSize is the fetcher's default
+		if limitedFetcher.MaxSize == 0 {
+			limitedFetcher.MaxSize = 1
+		}
+
+		resp, err := limitedFetcher.Get(attachment.ProxyURL)
 
 		if err != nil {
-			return []*discordgo.File{}, err
+			log.Printf("Failed to download attachment %v: %v", attachment.ID, err)
+			files.Skipped = append(files.Skipped, attachment)
+			continue
 		}
 
-		files[i] = &file
+		budget -= int64(attachment.Size)
+
+		files.bodies = append(files.bodies, resp.Body)
+		files.Files = append(files.Files, &discordgo.File{
+			ContentType: attachment.ContentType,
+			Name:        attachment.Filename,
+			Reader:      resp.Body,
+		})
 	}
 
-	return files, nil
+	return files
+}
+
+func (f *AttachmentFiles) Close() {
+	for _, body := range f.bodies {
+		body.Close()
+	}
 }
 
 // Returns true if (m1/d1) is earlier than (m2/d2)
@@ -66,6 +305,110 @@ func SameDates(m1, d1, m2, d2 int64) bool {
 	return m1 == m2 && d1 == d2
 }
 
+// Cuts text down to at most limit characters
+func Truncate(text string, limit int) string {
+	runes := []rune(text)
+
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit])
+}
+
+// Joins lines into chunks no longer than limit, for messages and embeds with length caps
+func ChunkLines(lines []string, separator string, limit int) []string {
+	chunks := []string{}
+	current := ""
+
+	for _, line := range lines {
+		line = Truncate(line, limit)
+
+		if current != "" && len(current)+len(separator)+len(line) > limit {
+			chunks = append(chunks, current)
+			current = ""
+		}
+
+		if current != "" {
+			current += separator
+		}
+
+		current += line
+	}
+
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+const DefaultBirthdayTemplate = "Friendly Reminder: Today, {name} ({mention}, {user_id}) was born!\n\nIt's their birthday 🎉🥳"
+
+var BirthdayTemplateVariables = []string{"name", "mention", "user_id", "age", "days_until"}
+
+// Returns the next date (m/d) falls on, current day included
+func NextDate(month, day int64, current time.Time) time.Time {
+	today := time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, time.UTC)
+	next := time.Date(current.Year(), time.Month(month), int(day), 0, 0, 0, 0, time.UTC)
+
+	if next.Before(today) {
+		next = next.AddDate(1, 0, 0)
+	}
+
+	return next
+}
+
+// Returns placeholder values of a birthday entry for its next occurrence
+func BirthdayTemplateValues(birthday models.Birthday, current time.Time) map[string]string {
+	today := time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, time.UTC)
+	next := NextDate(birthday.BirthMonth, birthday.BirthDay, current)
+
+	age := "?"
+	if birthday.BirthYear != 0 {
+		age = strconv.Itoa(next.Year() - int(birthday.BirthYear))
+	}
+
+	return map[string]string{
+		"name":       birthday.Name,
+		"mention":    fmt.Sprintf("<@%s>", birthday.UserId),
+		"user_id":    birthday.UserId,
+		"age":        age,
+		"days_until": strconv.Itoa(int(next.Sub(today).Hours() / 24)),
+	}
+}
+
+// Returns the author's reminder template, falling back to the guild's and then the default
+func BirthdayTemplate(db *gorm.DB, authorId, guildId string) (string, error) {
+	var authorTemplate models.BirthdayTemplate
+
+	result := db.Where(&models.BirthdayTemplate{AuthorId: authorId}).First(&authorTemplate)
+
+	switch {
+	case result.Error == nil:
+		return authorTemplate.Template, nil
+	case !errors.Is(result.Error, gorm.ErrRecordNotFound):
+		return "", result.Error
+	}
+
+	if guildId == "" {
+		return DefaultBirthdayTemplate, nil
+	}
+
+	var guildTemplate models.BirthdayTemplate
+
+	result = db.Where(&models.BirthdayTemplate{GuildId: guildId}).First(&guildTemplate)
+
+	switch {
+	case result.Error == nil:
+		return guildTemplate.Template, nil
+	case !errors.Is(result.Error, gorm.ErrRecordNotFound):
+		return "", result.Error
+	}
+
+	return DefaultBirthdayTemplate, nil
+}
+
 // Returns TreeNode from list of {"ParentId": Names of children}
 func ConstructTreeNode(m map[string][]string, rootParent string) trees.TreeNode {
 	root := trees.TreeNode{
@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"kodachi/bot/models"
+	"kodachi/packages/blobs"
+	"kodachi/packages/cards"
+	"kodachi/packages/fetcher"
+	"kodachi/packages/templates"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+var WelcomeTemplateVariables = []string{"mention", "username", "user_id", "server", "member_count", "join_number", "account_age", "new_account", "bot", "rules_channel", "inviter", "invite_code"}
+
+// Where welcomes are sent
+const (
+	WelcomeDeliveryChannel = "channel"
+	WelcomeDeliveryDM      = "dm"
+	WelcomeDeliveryBoth    = "both"
+)
+
+// Accounts younger than this are considered new, e.g. by the {new_account} conditional
+const NewAccountAge = 7 * 24 * time.Hour
+
+// Parses a welcome message, accepting the <@USER_ID> placeholder of older messages as {mention}
+func ParseWelcomeTemplate(text string) (*templates.Template, error) {
+	return templates.Parse(strings.ReplaceAll(text, "<@USER_ID>", "{mention}"), WelcomeTemplateVariables)
+}
+
+// Renders a stored welcome message. Messages saved before templates existed that don't parse as one,
+// e.g. because of literal braces, are sent as they were with only <@USER_ID> replaced.
+func RenderWelcomeTemplate(text string, values map[string]string) string {
+	template, err := ParseWelcomeTemplate(text)
+
+	if err != nil {
+		return strings.ReplaceAll(text, "<@USER_ID>", values["mention"])
+	}
+
+	return template.Render(values)
+}
+
+// Picks a message from the guild's welcome message pool at random by weight, skipping the most recently used ones.
+// Returns config.WelcomeMessage if the pool is empty, record marks the picked message as used.
+func PickWelcomeMessage(db *gorm.DB, config models.Config, record bool) (string, error) {
+	pool := []models.WelcomeMessage{}
+
+	result := db.Where(&models.WelcomeMessage{GuildId: config.GuildId}).Order("last_used_at DESC NULLS LAST").Find(&pool)
+
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	if len(pool) == 0 {
+		return config.WelcomeMessage, nil
+	}
+
+	// Always leave at least one message to pick from
+	skip := config.WelcomeMessageNoRepeat
+	if skip > len(pool)-1 {
+		skip = len(pool) - 1
+	}
+
+	candidates := []models.WelcomeMessage{}
+	totalWeight := 0
+
+	for i, message := range pool {
+		if i < skip && message.LastUsedAt != nil {
+			continue
+		}
+
+		candidates = append(candidates, message)
+		totalWeight += message.Weight
+	}
+
+	picked := candidates[0]
+	target := rand.Intn(totalWeight)
+
+	for _, message := range candidates {
+		if target < message.Weight {
+			picked = message
+			break
+		}
+
+		target -= message.Weight
+	}
+
+	if record {
+		db.Model(&picked).Update("last_used_at", time.Now().UTC())
+	}
+
+	return picked.Template, nil
+}
+
+// Returns placeholder values for welcoming user to a guild
+func WelcomeTemplateValues(db *gorm.DB, s *discordgo.Session, guildId string, user *discordgo.User) map[string]string {
+	values := map[string]string{
+		"mention":  fmt.Sprintf("<@%s>", user.ID),
+		"username": user.Username,
+		"user_id":  user.ID,
+		"bot":      strconv.FormatBool(user.Bot),
+	}
+
+	if created, err := discordgo.SnowflakeTimestamp(user.ID); err == nil {
+		age := time.Since(created)
+
+		values["account_age"] = FormatDuration(age)
+		values["new_account"] = strconv.FormatBool(age < NewAccountAge)
+	}
+
+	if guild, err := welcomeGuild(s, guildId); err == nil {
+		values["server"] = guild.Name
+		values["member_count"] = strconv.Itoa(guild.MemberCount)
+		values["join_number"] = Ordinal(guild.MemberCount)
+
+		if guild.RulesChannelID != "" {
+			values["rules_channel"] = fmt.Sprintf("<#%s>", guild.RulesChannelID)
+		}
+	}
+
+	var memberJoin models.MemberJoin
+
+	result := db.Where(&models.MemberJoin{GuildId: guildId, UserId: user.ID}).Order("id DESC").Limit(1).Find(&memberJoin)
+
+	if result.Error != nil {
+		log.Print(result.Error)
+	}
+
+	if memberJoin.InviteCode != "" {
+		values["invite_code"] = memberJoin.InviteCode
+	}
+
+	if memberJoin.InviterId != "" {
+		values["inviter"] = fmt.Sprintf("<@%s>", memberJoin.InviterId)
+	}
+
+	return values
+}
+
+// Returns the guild from state, or fetched with its member count if it isn't cached
+func welcomeGuild(s *discordgo.Session, guildId string) (*discordgo.Guild, error) {
+	guild, err := s.State.Guild(guildId)
+
+	if err != nil {
+		guild, err = s.GuildWithCounts(guildId)
+
+		if err == nil {
+			guild.MemberCount = guild.ApproximateMemberCount
+		}
+	}
+
+	return guild, err
+}
+
+var welcomeCardBackgroundFetcher = &fetcher.Fetcher{
+	ContentTypes: []string{"image/png", "image/jpeg", "image/gif"},
+}
+
+const WelcomeCardFileName = "welcome-card.png"
+
+// Renders the welcome card of user as a file named WelcomeCardFileName
+func WelcomeCard(db *gorm.DB, s *discordgo.Session, config models.Config, user *discordgo.User) (*discordgo.File, error) {
+	card := cards.WelcomeCard{
+		BackgroundColor: FormatHexColor(config.WelcomeCardBackgroundColor),
+		Username:        user.Username,
+	}
+
+	if guild, err := welcomeGuild(s, config.GuildId); err == nil {
+		card.MemberNumber = guild.MemberCount
+	}
+
+	avatar, err := s.UserAvatarDecode(user)
+
+	if err != nil {
+		log.Printf("Failed to fetch avatar of %v: %v", user.ID, err)
+	} else {
+		card.Avatar = avatar
+	}
+
+	if config.WelcomeCardBackgroundURL != "" {
+		background, err := welcomeCardBackground(db, config)
+
+		if err != nil {
+			log.Printf("Failed to fetch welcome card background of %v: %v", config.GuildId, err)
+		} else {
+			card.Background = background
+		}
+	}
+
+	png, err := cards.DrawWelcomeCard(card)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordgo.File{
+		Name:        WelcomeCardFileName,
+		ContentType: "image/png",
+		Reader:      bytes.NewReader(png),
+	}, nil
+}
+
+// Returns the card background of a guild, downloading it into the blob store on first use so that it's only downloaded once
+func welcomeCardBackground(db *gorm.DB, config models.Config) (image.Image, error) {
+	if config.WelcomeCardBackgroundKey == "" {
+		data, _, err := welcomeCardBackgroundFetcher.Fetch(config.WelcomeCardBackgroundURL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := blobs.Put(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Only if the URL wasn't changed during the download
+		result := db.Model(&models.Config{}).
+			Where(&models.Config{GuildId: config.GuildId, WelcomeCardBackgroundURL: config.WelcomeCardBackgroundURL}).
+			Update("welcome_card_background_key", key)
+
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		config.WelcomeCardBackgroundKey = key
+	}
+
+	data, err := blobs.Read(config.WelcomeCardBackgroundKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	background, _, err := image.Decode(bytes.NewReader(data))
+
+	return background, err
+}
+
+// Renders the welcome message and embed configured for a guild, the embed is nil if not configured
+func RenderWelcome(db *gorm.DB, s *discordgo.Session, config models.Config, user *discordgo.User) (string, *discordgo.MessageEmbed, error) {
+	values := WelcomeTemplateValues(db, s, config.GuildId, user)
+
+	content := RenderWelcomeTemplate(config.WelcomeMessage, values)
+
+	embedTemplate := WelcomeEmbedTemplate(config)
+
+	if !embedTemplate.IsSet() {
+		return content, nil, nil
+	}
+
+	// Show the welcome card inside the embed
+	if embedTemplate.ImageURL == "" && config.WelcomeCardEnabled {
+		embedTemplate.ImageURL = "attachment://" + WelcomeCardFileName
+	}
+
+	embed, err := embedTemplate.Render(WelcomeTemplateVariables, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+		URL: user.AvatarURL("256"),
+	}
+
+	return content, embed, nil
+}
+
+// Parses colors formatted as "#RRGGBB" or "RRGGBB"
+func ParseHexColor(value string) (int, error) {
+	color, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(value), "#"), 16, 32)
+
+	if err != nil || color > 0xFFFFFF {
+		return 0, fmt.Errorf("%q is not a hex color like #5865F2", value)
+	}
+
+	return int(color), nil
+}
+
+// Formats a color as "#RRGGBB", or "" for no color
+func FormatHexColor(color int) string {
+	if color == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("#%06X", color)
+}
+
+// Formats a duration in its largest whole unit, e.g. "3 days" or "2 years"
+func FormatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+
+	units := []struct {
+		name   string
+		length time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, unit := range units {
+		if count := int(d / unit.length); count > 0 {
+			if count == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+
+			return fmt.Sprintf("%v %ss", count, unit.name)
+		}
+	}
+
+	return "less than a minute"
+}